@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/ilam072/image-processor/intenal/config"
+	"github.com/ilam072/image-processor/intenal/image/gc"
+	imagerepo "github.com/ilam072/image-processor/intenal/image/repo/postgres"
+	imageservice "github.com/ilam072/image-processor/intenal/image/service"
+	"github.com/ilam072/image-processor/intenal/storage/backend"
+	taskrepo "github.com/ilam072/image-processor/intenal/task/repo/postgres"
+	"github.com/ilam072/image-processor/pkg/db"
+	"github.com/wb-go/wbf/zlog"
+	"os/signal"
+	"syscall"
+)
+
+// gc — разовый проход сборщика: сверяет объекты бакета с images и tasks,
+// печатает расхождения и удаляет объекты без ссылок старше grace-периода.
+// По умолчанию только печатает отчёт; удаление включается явно:
+//
+//	go run ./cmd/gc -dry-run=false -grace=24h
+func main() {
+	// Initialize logger
+	zlog.Init()
+
+	// Context
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	// Initialize config, flags override it
+	cfg := config.MustLoad()
+	dryRun := flag.Bool("dry-run", cfg.GC.DryRun, "only report discrepancies, do not delete anything; pass -dry-run=false to delete")
+	grace := flag.Duration("grace", cfg.GC.GracePeriod, "do not delete objects younger than this")
+	flag.Parse()
+
+	// Connect to DB
+	DB, err := db.OpenDB(cfg.DB)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to connect to DB")
+	}
+	defer func() { _ = DB.Master.Close() }()
+
+	// Initialize file image storage
+	imageStorage, err := backend.New(ctx, cfg)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to initialize image storage")
+	}
+
+	// Initialize collector
+	imageRepo := imagerepo.New(DB)
+	image := imageservice.New(imageRepo, imageStorage, imageservice.Opts{})
+	collector := gc.New(imageRepo, taskrepo.New(DB), imageStorage, image, gc.Opts{
+		GracePeriod: *grace,
+		DryRun:      *dryRun,
+	})
+
+	report, err := collector.Run(ctx)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("gc failed")
+	}
+
+	for _, obj := range report.Orphans {
+		zlog.Logger.Info().Str("object", obj.Name).Int64("size", obj.Size).Time("last_modified", obj.LastModified).Msg("orphaned object")
+	}
+	for _, ID := range report.StalledDeletes {
+		zlog.Logger.Info().Str("image_id", ID.String()).Msg("image deletion was not finished")
+	}
+	for _, ID := range report.MissingOriginals {
+		zlog.Logger.Warn().Str("image_id", ID.String()).Msg("original object is missing")
+	}
+	for _, ID := range report.MissingResults {
+		zlog.Logger.Warn().Int("task_id", ID).Msg("completed task result is missing")
+	}
+
+	zlog.Logger.Info().
+		Bool("dry_run", *dryRun).
+		Dur("grace", *grace).
+		Int("orphans", len(report.Orphans)).
+		Int("recent", report.Recent).
+		Int("deleted", report.Deleted).
+		Int("stalled_deletes", len(report.StalledDeletes)).
+		Int("missing_originals", len(report.MissingOriginals)).
+		Int("missing_results", len(report.MissingResults)).
+		Msg("gc finished")
+}
@@ -2,26 +2,40 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/ilam072/image-processor/intenal/config"
-	storage "github.com/ilam072/image-processor/intenal/image/filestorage/minio"
-	"github.com/ilam072/image-processor/intenal/image/kafka/consumer"
-	"github.com/ilam072/image-processor/intenal/image/kafka/handler"
-	"github.com/ilam072/image-processor/intenal/image/kafka/producer"
 	"github.com/ilam072/image-processor/intenal/image/processor"
+	"github.com/ilam072/image-processor/intenal/image/queue/handler"
+	"github.com/ilam072/image-processor/intenal/image/queue/producer"
 	imagerepo "github.com/ilam072/image-processor/intenal/image/repo/postgres"
 	imagerest "github.com/ilam072/image-processor/intenal/image/rest"
 	imageservice "github.com/ilam072/image-processor/intenal/image/service"
+	"github.com/ilam072/image-processor/intenal/image/transform"
 	"github.com/ilam072/image-processor/intenal/middlewares"
+	presetrepo "github.com/ilam072/image-processor/intenal/preset/repo/postgres"
+	presetrest "github.com/ilam072/image-processor/intenal/preset/rest"
+	presetservice "github.com/ilam072/image-processor/intenal/preset/service"
+	"github.com/ilam072/image-processor/intenal/queue"
+	queuekafka "github.com/ilam072/image-processor/intenal/queue/kafka"
+	queuememory "github.com/ilam072/image-processor/intenal/queue/memory"
+	queuepostgres "github.com/ilam072/image-processor/intenal/queue/postgres"
+	"github.com/ilam072/image-processor/intenal/signer"
+	"github.com/ilam072/image-processor/intenal/storage/backend"
+	"github.com/ilam072/image-processor/intenal/task/outbox"
+	"github.com/ilam072/image-processor/intenal/task/reaper"
 	taskrepo "github.com/ilam072/image-processor/intenal/task/repo/postgres"
 	taskrest "github.com/ilam072/image-processor/intenal/task/rest"
 	taskservice "github.com/ilam072/image-processor/intenal/task/service"
+	"github.com/ilam072/image-processor/intenal/types/domain"
 	"github.com/ilam072/image-processor/pkg/db"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/wb-go/wbf/dbpg"
 	"github.com/wb-go/wbf/ginext"
 	"github.com/wb-go/wbf/zlog"
 	"net/http"
+	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -43,59 +57,159 @@ func main() {
 		zlog.Logger.Fatal().Err(err).Msg("failed to connect to DB")
 	}
 
-	// Initialize minio client
-	minioClient, err := minio.New(cfg.Minio.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.Minio.RootUser, cfg.Minio.RootPassword, ""),
-		Secure: cfg.Minio.UseSSL,
-	})
-	if err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to instantiate minio client")
-	}
-
-	exists, err := minioClient.BucketExists(ctx, cfg.Minio.BucketName)
+	// Initialize file image storage
+	imageStorage, err := backend.New(ctx, cfg)
 	if err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("failed to check if bucket exists")
-	}
-
-	if !exists {
-		if err := minioClient.MakeBucket(ctx, cfg.Minio.BucketName, minio.MakeBucketOptions{}); err != nil {
-			zlog.Logger.Fatal().Err(err).Msg("failed to create bucket")
-		}
+		zlog.Logger.Fatal().Err(err).Msg("failed to initialize image storage")
 	}
 
-	// Initialize file image storage
-	imageStorage := storage.New(minioClient, cfg.Minio.BucketName)
-
 	// Initialize image processor
 	opts := processor.Opts{
 		Width:  800,
 		Height: 800,
+
+		WatermarkText: cfg.Watermark.Text,
+		WatermarkLogo: cfg.Watermark.Logo,
+		FontsDir:      cfg.Watermark.FontsDir,
+		LogosDir:      cfg.Watermark.LogosDir,
 	}
 	imageProcessor := processor.NewProcessor(opts)
 
 	// Initialize producer and consumer
-	producerr := producer.New(cfg.Kafka.Brokers, cfg.Kafka.Topic)
-	consumerr := consumer.New(cfg.Kafka.Brokers, cfg.Kafka.Topic, cfg.Kafka.GroupID)
+	taskPublisher, dlqPublisher, consumerr, err := newQueue(cfg, DB)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to initialize queue")
+	}
+	producerr := producer.New(taskPublisher)
+	dlqProducer := producer.New(dlqPublisher)
 
-	// Initialize image and task repositories
+	// Initialize image, task and preset repositories
 	imageRepo := imagerepo.New(DB)
 	taskRepo := taskrepo.New(DB)
+	presetRepo := presetrepo.New(DB)
+
+	// Initialize image, task and preset services
+	allowedFormats := make([]domain.ImageFormat, 0, len(cfg.Upload.AllowedFormats))
+	for _, format := range cfg.Upload.AllowedFormats {
+		allowedFormats = append(allowedFormats, domain.ImageFormat(strings.ToLower(strings.TrimSpace(format))))
+	}
+	image := imageservice.New(imageRepo, imageStorage, imageservice.Opts{
+		MaxSize:        cfg.Upload.MaxSize,
+		MaxWidth:       cfg.Upload.MaxWidth,
+		MaxHeight:      cfg.Upload.MaxHeight,
+		MaxPixels:      cfg.Upload.MaxPixels,
+		AllowedFormats: allowedFormats,
+	})
+	variantPresets, err := domain.ParseVariantPresets(cfg.Variants.Presets)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to parse variant presets")
+	}
+	task := taskservice.New(imageRepo, taskRepo, presetRepo, taskservice.Opts{
+		VariantPresets: variantPresets,
+		PresetCacheTTL: cfg.Presets.CacheTTL,
+	})
+	preset := presetservice.New(presetRepo, task)
+
+	// Initialize outbox relay
+	relay := outbox.New(taskRepo, producerr, outbox.Opts{
+		PollInterval: cfg.Outbox.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+	})
 
-	// Initialize image and task services
-	image := imageservice.New(imageRepo, imageStorage)
-	task := taskservice.New(imageRepo, taskRepo, producerr)
+	// Initialize queue handler
+	workerID := cfg.Worker.ID
+	if workerID == "" {
+		hostname, _ := os.Hostname()
+		workerID = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	}
+	kafkaHandler := handler.New(task, image, imageStorage, imageProcessor, consumerr, dlqProducer, handler.Opts{
+		WorkerID: workerID,
+		Retry: handler.RetryPolicy{
+			MaxRetries: cfg.Kafka.MaxRetries,
+			BaseDelay:  cfg.Kafka.RetryBaseDelay,
+			MaxDelay:   cfg.Kafka.RetryMaxDelay,
+		},
+		Workers:     cfg.Kafka.Workers,
+		MaxInFlight: cfg.Kafka.MaxInFlight,
+		LeaseTTL:    cfg.Reaper.LeaseTTL,
+	})
 
-	// Initialize Kafka handler
-	kafkaHandler := handler.New(task, image, imageStorage, imageProcessor, consumerr)
+	// Initialize stuck task reaper
+	taskReaper := reaper.New(taskRepo, reaper.Opts{
+		Interval:    cfg.Reaper.Interval,
+		LeaseTTL:    cfg.Reaper.LeaseTTL,
+		MaxAttempts: cfg.Reaper.MaxAttempts,
+		BatchSize:   cfg.Reaper.BatchSize,
+	})
 
-	// Initialize image and task rest api handlers
+	// Initialize image, task and preset rest api handlers
 	taskHandler := taskrest.NewTaskHandler(task)
-	imageHandler := imagerest.NewImageHandler(image, imageStorage)
+	presetHandler := presetrest.NewPresetHandler(preset)
+	transformer := transform.New(image, imageStorage, imageProcessor, transform.Opts{
+		MaxConcurrent: cfg.Transform.MaxConcurrent,
+	})
+	// Initialize url signer
+	var (
+		verifier  middlewares.Verifier
+		urlSigner imagerest.Signer
+	)
+	signingKeys, err := signer.ParseKeys(cfg.Signing.Keys)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("failed to parse signing keys")
+	}
+	switch {
+	case cfg.Signing.Disabled:
+		zlog.Logger.Warn().Msg("SIGNING_DISABLED is set, image urls are not signed")
+	case len(signingKeys) == 0:
+		// Без ключей все изображения отдавались бы без подписи, поэтому отключение должно быть явным
+		zlog.Logger.Fatal().Msg("SIGNING_KEYS is empty, configure keys or set SIGNING_DISABLED=true")
+	case len(cfg.Signing.IssuerTokens) == 0:
+		// Иначе получить подписанную ссылку не смог бы никто
+		zlog.Logger.Fatal().Msg("SIGNING_ISSUER_TOKENS is empty, configure tokens of services that issue image urls")
+	default:
+		s, err := signer.New(signingKeys, cfg.Signing.ActiveKey)
+		if err != nil {
+			zlog.Logger.Fatal().Err(err).Msg("failed to initialize url signer")
+		}
+		verifier, urlSigner = s, s
+	}
+
+	imageHandler := imagerest.NewImageHandler(image, imageStorage, task, transformer, urlSigner, imagerest.Opts{
+		MaxUploadSize: cfg.Upload.MaxSize,
+		SignTTL:       cfg.Signing.TTL,
+		SignMaxTTL:    cfg.Signing.MaxTTL,
+
+		CacheControlOriginal:   cfg.Cache.Original,
+		CacheControlDerivative: cfg.Cache.Derivative,
+	})
+
+	// Start queue handler. Обработка взятых задач отменяется, только если они не успели
+	// завершиться за drain timeout
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	handlerDone := make(chan struct{})
+	go func() {
+		defer close(handlerDone)
+		if err := kafkaHandler.Start(ctx, workCtx); err != nil && !errors.Is(err, context.Canceled) {
+			zlog.Logger.Fatal().Err(err).Msg("failed to start queue handler")
+		}
+	}()
 
-	// Start Kafka handler
+	// Start outbox relay
+	relayDone := make(chan struct{})
 	go func() {
-		if err := kafkaHandler.Start(ctx); err != nil {
-			zlog.Logger.Fatal().Err(err).Msg("failed to start kafka handler")
+		defer close(relayDone)
+		if err := relay.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			zlog.Logger.Error().Err(err).Msg("outbox relay stopped")
+		}
+	}()
+
+	// Start stuck task reaper
+	reaperDone := make(chan struct{})
+	go func() {
+		defer close(reaperDone)
+		if err := taskReaper.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			zlog.Logger.Error().Err(err).Msg("task reaper stopped")
 		}
 	}()
 
@@ -106,17 +220,65 @@ func main() {
 	engine.Use(middlewares.CORS())
 
 	// POST /api/upload
-	// GET /api/image/:id?processed=
-	// GET /api/image/:id/task/resize
+	// GET /api/image/:id?processed=<task type>|<preset> (signed)
+	// GET /api/image/:id/url?target=image|task|preset|transform&ttl= (issuer token, если подпись включена)
+	// GET /api/image/:id/metadata
+	// GET /api/image/:id/variants?preset= (issuer token, если подпись включена)
+	// GET /api/image/:id/transform?w=&h=&fit=&filter=&anchor=&fmt=&q=&compression= (signed)
+	// GET /api/image/:id/task/resize?width=&height=&fit=&filter=&anchor=
 	// GET /api/image/:id/task/thumbnail
-	// GET /api/image/:id/task/watermark
+	// GET /api/image/:id/task/watermark?text=&font=&font_size=&color=&logo=&logo_scale=&anchor=&offset_x=&offset_y=&opacity=&rotation=&mode=single|tile
+	// GET /api/image/:id/task/crop?x=&y=&width=&height=
+	// GET /api/image/:id/task/aspect_crop?aspect=16:9&anchor=
+	// GET /api/image/:id/task/smart_crop?width=&height=
+	// GET /api/image/:id/task/rotate?angle=&background=#rrggbb|transparent
+	// GET /api/image/:id/task/flip?direction=horizontal|vertical|both
+	// GET /api/image/:id/task/variants?preset=
+	// POST /api/image/:id/task/pipeline
+	// POST /api/image/:id/task/:preset
+	// GET /api/image/:id/tasks?limit=&offset=
+	// GET /api/task/:id
+	// GET /api/task/:id/attempts
+	// GET /api/task/:id/result (signed)
+	// POST /api/presets
+	// GET /api/presets
+	// GET /api/presets/:name
+	// PUT /api/presets/:name
+	// DELETE /api/presets/:name
+	signed := middlewares.SignedURL(verifier)
+	// Без подписи ссылки ничего не защищают, поэтому при SIGNING_DISABLED токен выдачи не требуется
+	issuer := func(c *ginext.Context) { c.Next() }
+	if urlSigner != nil {
+		issuer = middlewares.IssuerToken(cfg.Signing.IssuerTokens)
+	}
 	apiGroup := engine.Group("/api")
 	apiGroup.POST("/upload", imageHandler.UploadImage)
-	apiGroup.GET("/image/:id", imageHandler.GetImage) // query ?processed=resize;thumbnail;watermark
+	apiGroup.GET("/image/:id", signed, imageHandler.GetImage) // query ?processed=<task type> (+ task params; variants: preset, width, format) или ?processed=<preset>
+	apiGroup.GET("/image/:id/url", issuer, imageHandler.SignURL)
+	apiGroup.GET("/image/:id/metadata", imageHandler.GetImageMetadata)
+	apiGroup.GET("/image/:id/variants", issuer, imageHandler.GetVariants)
+	apiGroup.GET("/image/:id/transform", signed, imageHandler.Transform)
 	apiGroup.GET("/image/:id/task/resize", taskHandler.EnqueueTask("resize"))
 	apiGroup.GET("/image/:id/task/thumbnail", taskHandler.EnqueueTask("thumbnail"))
 	apiGroup.GET("/image/:id/task/watermark", taskHandler.EnqueueTask("watermark"))
+	apiGroup.GET("/image/:id/task/crop", taskHandler.EnqueueTask("crop"))
+	apiGroup.GET("/image/:id/task/aspect_crop", taskHandler.EnqueueTask("aspect_crop"))
+	apiGroup.GET("/image/:id/task/smart_crop", taskHandler.EnqueueTask("smart_crop"))
+	apiGroup.GET("/image/:id/task/rotate", taskHandler.EnqueueTask("rotate"))
+	apiGroup.GET("/image/:id/task/flip", taskHandler.EnqueueTask("flip"))
+	apiGroup.GET("/image/:id/task/variants", taskHandler.EnqueueTask("variants"))
+	apiGroup.POST("/image/:id/task/pipeline", taskHandler.EnqueuePipeline)
+	apiGroup.POST("/image/:id/task/:preset", taskHandler.EnqueuePreset)
+	apiGroup.GET("/image/:id/tasks", taskHandler.ListImageTasks)
+	apiGroup.GET("/task/:id", taskHandler.GetTask)
+	apiGroup.GET("/task/:id/attempts", taskHandler.ListTaskAttempts)
+	apiGroup.GET("/task/:id/result", signed, imageHandler.GetTaskResult)
 	apiGroup.DELETE("/image/:id", imageHandler.DeleteImage)
+	apiGroup.POST("/presets", presetHandler.CreatePreset)
+	apiGroup.GET("/presets", presetHandler.ListPresets)
+	apiGroup.GET("/presets/:name", presetHandler.GetPreset)
+	apiGroup.PUT("/presets/:name", presetHandler.UpdatePreset)
+	apiGroup.DELETE("/presets/:name", presetHandler.DeletePreset)
 
 	// Initialize and start http server
 	server := &http.Server{
@@ -125,7 +287,7 @@ func main() {
 	}
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			zlog.Logger.Fatal().Err(err).Msg("failed to listen start http server")
 		}
 	}()
@@ -140,15 +302,67 @@ func main() {
 		zlog.Logger.Error().Err(err).Msg("server shutdown failed")
 	}
 
-	if err := DB.Master.Close(); err != nil {
-		zlog.Logger.Error().Err(err).Msg("failed to close master database")
+	// Wait for in-flight tasks before closing consumer and DB
+	drainTimeout := cfg.Kafka.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = 30 * time.Second
+	}
+	select {
+	case <-handlerDone:
+	case <-time.After(drainTimeout):
+		zlog.Logger.Warn().Msg("timed out waiting for in-flight tasks, canceling them")
+		cancelWork()
+		select {
+		case <-handlerDone:
+		case <-time.After(5 * time.Second):
+			zlog.Logger.Error().Msg("in-flight tasks did not stop after cancellation")
+		}
 	}
+	<-relayDone
+	<-reaperDone
 
+	// Консьюмер и издатели закрываются раньше базы: воркеры и relay пишут в неё до последнего
 	if err := consumerr.Close(); err != nil {
-		zlog.Logger.Error().Err(err).Msg("failed to close kafka consumer")
+		zlog.Logger.Error().Err(err).Msg("failed to close queue consumer")
 	}
 
 	if err := producerr.Close(); err != nil {
-		zlog.Logger.Error().Err(err).Msg("failed to close kafka producer")
+		zlog.Logger.Error().Err(err).Msg("failed to close queue producer")
+	}
+
+	if err := dlqProducer.Close(); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to close queue dead letter producer")
+	}
+
+	if err := DB.Master.Close(); err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to close master database")
+	}
+}
+
+// newQueue создаёт издателей задач и DLQ и консьюмер задач для QUEUE_BACKEND.
+func newQueue(cfg *config.Config, DB *dbpg.DB) (tasks queue.Publisher, dlq queue.Publisher, consumer queue.Consumer, err error) {
+	switch cfg.Queue.Backend {
+	case "", "kafka":
+		return queuekafka.NewPublisher(cfg.Kafka.Brokers, cfg.Kafka.Topic),
+			queuekafka.NewPublisher(cfg.Kafka.Brokers, cfg.Kafka.DLQTopic),
+			queuekafka.NewConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topic, cfg.Kafka.GroupID),
+			nil
+	case "postgres":
+		opts := queuepostgres.ConsumerOpts{
+			PollInterval:      cfg.Queue.PollInterval,
+			VisibilityTimeout: cfg.Queue.VisibilityTimeout,
+		}
+		return queuepostgres.NewPublisher(DB, cfg.Kafka.Topic),
+			queuepostgres.NewPublisher(DB, cfg.Kafka.DLQTopic),
+			queuepostgres.NewConsumer(DB, cfg.Kafka.Topic, opts),
+			nil
+	case "memory":
+		broker := queuememory.NewBroker(0)
+		return broker.Publisher(cfg.Kafka.Topic),
+			broker.Publisher(cfg.Kafka.DLQTopic),
+			broker.Consumer(cfg.Kafka.Topic),
+			nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown queue backend %q", cfg.Queue.Backend)
 	}
 }
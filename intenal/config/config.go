@@ -7,10 +7,23 @@ import (
 )
 
 type Config struct {
-	DB     DBConfig     `mapstructure:",squash"`
-	Server ServerConfig `mapstructure:",squash"`
-	Minio  MinioConfig  `mapstructure:",squash"`
-	Kafka  KafkaConfig  `mapstructure:",squash"`
+	DB        DBConfig        `mapstructure:",squash"`
+	Server    ServerConfig    `mapstructure:",squash"`
+	Storage   StorageConfig   `mapstructure:",squash"`
+	Minio     MinioConfig     `mapstructure:",squash"`
+	Upload    UploadConfig    `mapstructure:",squash"`
+	Transform TransformConfig `mapstructure:",squash"`
+	Watermark WatermarkConfig `mapstructure:",squash"`
+	Variants  VariantsConfig  `mapstructure:",squash"`
+	Presets   PresetsConfig   `mapstructure:",squash"`
+	Signing   SigningConfig   `mapstructure:",squash"`
+	Cache     CacheConfig     `mapstructure:",squash"`
+	Queue     QueueConfig     `mapstructure:",squash"`
+	Kafka     KafkaConfig     `mapstructure:",squash"`
+	Worker    WorkerConfig    `mapstructure:",squash"`
+	Outbox    OutboxConfig    `mapstructure:",squash"`
+	Reaper    ReaperConfig    `mapstructure:",squash"`
+	GC        GCConfig        `mapstructure:",squash"`
 }
 
 type DBConfig struct {
@@ -29,6 +42,11 @@ type ServerConfig struct {
 	HTTPPort string `mapstructure:"HTTP_PORT"`
 }
 
+type StorageConfig struct {
+	Backend  string `mapstructure:"STORAGE_BACKEND"`   // minio | local | memory
+	LocalDir string `mapstructure:"STORAGE_LOCAL_DIR"` // local: каталог для файлов
+}
+
 type MinioConfig struct {
 	Endpoint     string `mapstructure:"MINIO_ENDPOINT"`    // Адрес конечной точки
 	BucketName   string `mapstructure:"MINIO_BUCKET_NAME"` // Название конкретного бакета в
@@ -38,10 +56,93 @@ type MinioConfig struct {
 
 }
 
+type UploadConfig struct {
+	MaxSize        int64    `mapstructure:"UPLOAD_MAX_SIZE"`        // Максимальный размер файла в байтах
+	MaxWidth       int      `mapstructure:"UPLOAD_MAX_WIDTH"`       // Максимальная ширина в пикселях
+	MaxHeight      int      `mapstructure:"UPLOAD_MAX_HEIGHT"`      // Максимальная высота в пикселях
+	MaxPixels      int64    `mapstructure:"UPLOAD_MAX_PIXELS"`      // Максимум пикселей, защита от decompression bomb
+	AllowedFormats []string `mapstructure:"UPLOAD_ALLOWED_FORMATS"` // jpeg,png,gif,bmp,tiff
+}
+
+type TransformConfig struct {
+	MaxConcurrent int `mapstructure:"TRANSFORM_MAX_CONCURRENT"` // Сколько преобразований на лету выполняется одновременно
+}
+
+// WatermarkConfig — водяной знак по умолчанию и каталоги ресурсов, на которые ссылаются задачи.
+type WatermarkConfig struct {
+	Text     string `mapstructure:"WATERMARK_TEXT"`      // Текст по умолчанию
+	Logo     string `mapstructure:"WATERMARK_LOGO"`      // Логотип по умолчанию вместо текста (файл в WATERMARK_LOGOS_DIR)
+	FontsDir string `mapstructure:"WATERMARK_FONTS_DIR"` // Каталог шрифтов TTF/OTF
+	LogosDir string `mapstructure:"WATERMARK_LOGOS_DIR"` // Каталог PNG-логотипов
+}
+
+// VariantsConfig — пресеты наборов версий для srcset, которые клиент запрашивает по имени.
+type VariantsConfig struct {
+	Presets []string `mapstructure:"VARIANT_PRESETS"` // name:w1|w2|...[:format1|format2] через запятую
+}
+
+// PresetsConfig — пресеты преобразований, хранящиеся в БД.
+type PresetsConfig struct {
+	CacheTTL time.Duration `mapstructure:"PRESET_CACHE_TTL"` // Сколько кэшировать поиск пресета по имени, по умолчанию 5s
+}
+
+// SigningConfig — подпись ссылок на изображения. Без ключей сервер не запускается,
+// если подпись не отключена явно через SIGNING_DISABLED.
+type SigningConfig struct {
+	Disabled  bool          `mapstructure:"SIGNING_DISABLED"`   // Отдавать изображения без подписи (только для разработки)
+	Keys      []string      `mapstructure:"SIGNING_KEYS"`       // Ключи в формате kid:secret через запятую
+	ActiveKey string        `mapstructure:"SIGNING_ACTIVE_KEY"` // kid ключа, которым подписываются новые ссылки
+	TTL       time.Duration `mapstructure:"SIGNING_TTL"`        // Срок действия ссылки по умолчанию
+	MaxTTL    time.Duration `mapstructure:"SIGNING_MAX_TTL"`    // Максимальный срок действия ссылки
+
+	IssuerTokens []string `mapstructure:"SIGNING_ISSUER_TOKENS"` // Токены сервисов, которым разрешено получать подписанные ссылки
+}
+
+type CacheConfig struct {
+	Original   string `mapstructure:"CACHE_CONTROL_ORIGINAL"`   // Cache-Control для оригиналов
+	Derivative string `mapstructure:"CACHE_CONTROL_DERIVATIVE"` // Cache-Control для обработанных изображений
+}
+
+type QueueConfig struct {
+	Backend           string        `mapstructure:"QUEUE_BACKEND"`            // kafka | postgres | memory
+	PollInterval      time.Duration `mapstructure:"QUEUE_POLL_INTERVAL"`      // postgres: пауза между опросами пустой очереди
+	VisibilityTimeout time.Duration `mapstructure:"QUEUE_VISIBILITY_TIMEOUT"` // postgres: через сколько неподтверждённое сообщение выдаётся снова
+}
+
+// KafkaConfig — параметры Kafka. Топики, повторы и параллелизм используются любым backend'ом очереди.
 type KafkaConfig struct {
-	Brokers []string `mapstructure:"KAFKA_BROKERS"`
-	Topic   string   `mapstructure:"KAFKA_TOPIC"`
-	GroupID string   `mapstructure:"KAFKA_GROUP_ID"`
+	Brokers        []string      `mapstructure:"KAFKA_BROKERS"`
+	Topic          string        `mapstructure:"KAFKA_TOPIC"`
+	GroupID        string        `mapstructure:"KAFKA_GROUP_ID"`
+	DLQTopic       string        `mapstructure:"KAFKA_DLQ_TOPIC"`        // Топик для задач, которые не удалось обработать
+	MaxRetries     int           `mapstructure:"KAFKA_MAX_RETRIES"`      // Максимум повторов для временных ошибок
+	RetryBaseDelay time.Duration `mapstructure:"KAFKA_RETRY_BASE_DELAY"` // Задержка перед первым повтором
+	RetryMaxDelay  time.Duration `mapstructure:"KAFKA_RETRY_MAX_DELAY"`  // Верхняя граница задержки
+	Workers        int           `mapstructure:"KAFKA_WORKERS"`          // Количество воркеров обработки
+	MaxInFlight    int           `mapstructure:"KAFKA_MAX_IN_FLIGHT"`    // Максимум сообщений в обработке одновременно
+	DrainTimeout   time.Duration `mapstructure:"KAFKA_DRAIN_TIMEOUT"`    // Сколько ждать завершения задач при остановке
+}
+
+type WorkerConfig struct {
+	ID string `mapstructure:"WORKER_ID"` // Идентификатор воркера в истории попыток, по умолчанию hostname:pid
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"` // Период опроса outbox
+	BatchSize    int           `mapstructure:"OUTBOX_BATCH_SIZE"`    // Сколько записей отправлять за раз
+}
+
+type ReaperConfig struct {
+	Interval    time.Duration `mapstructure:"REAPER_INTERVAL"`   // Период поиска зависших задач
+	BatchSize   int           `mapstructure:"REAPER_BATCH_SIZE"` // Сколько задач обрабатывать за проход
+	LeaseTTL    time.Duration `mapstructure:"TASK_LEASE_TTL"`    // Время аренды задачи воркером
+	MaxAttempts int           `mapstructure:"TASK_MAX_ATTEMPTS"` // После стольких попыток зависшая задача помечается failed
+}
+
+// GCConfig — сборщик объектов без ссылок (cmd/gc).
+type GCConfig struct {
+	GracePeriod time.Duration `mapstructure:"GC_GRACE_PERIOD"` // Объекты моложе этого срока не удаляются
+	DryRun      bool          `mapstructure:"GC_DRY_RUN"`      // Только отчёт, без удаления (по умолчанию true)
 }
 
 func MustLoad() *Config {
@@ -50,7 +151,10 @@ func MustLoad() *Config {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	var cfg Config
+	// Значения по умолчанию, которые не перезаписываются, если ключа нет в конфиге
+	cfg := Config{
+		GC: GCConfig{DryRun: true},
+	}
 
 	if err := c.Unmarshal(&cfg); err != nil {
 		log.Fatalf("failed to unmarshal config: %v", err)
@@ -0,0 +1,248 @@
+package gc
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ilam072/image-processor/intenal/image/transform"
+	"github.com/ilam072/image-processor/intenal/storage"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/intenal/utils"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"github.com/wb-go/wbf/zlog"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	originalPrefix  = "original/"
+	processedPrefix = "processed/"
+
+	defaultGracePeriod = 24 * time.Hour
+	defaultPageSize    = 1000
+)
+
+type ImageRepo interface {
+	ListImagesPage(ctx context.Context, after uuid.UUID, limit int) ([]domain.Image, error)
+}
+
+type TaskRepo interface {
+	ListTasksPage(ctx context.Context, afterID int, limit int) ([]domain.Task, error)
+}
+
+type ImageDeleter interface {
+	DeleteImage(ctx context.Context, ID uuid.UUID) error
+}
+
+// Opts — параметры сборщика мусора.
+type Opts struct {
+	GracePeriod time.Duration // объекты и незавершённые удаления моложе этого срока не трогаются
+	DryRun      bool          // только отчёт, без удаления
+	PageSize    int           // сколько строк images и tasks читать за один запрос
+}
+
+// Report — расхождения между хранилищем и БД, найденные за один проход.
+type Report struct {
+	Orphans          []domain.ObjectInfo // объекты, на которые не ссылается ни изображение, ни задача
+	Recent           int                 // объекты без ссылок, но моложе GracePeriod
+	Deleted          int                 // сколько объектов-сирот удалено
+	StalledDeletes   []uuid.UUID         // изображения, удаление которых не было доведено до конца
+	MissingOriginals []uuid.UUID         // изображения, оригинала которых нет в хранилище
+	MissingResults   []int               // завершённые задачи, результата которых нет в хранилище
+}
+
+// Collector сверяет объекты original/ и processed/ с таблицами images и tasks.
+// Объект processed/ живого изображения нужен, если это результат задачи, которая выполнена
+// или ещё выполняется, либо производное преобразования на лету: у таких производных нет
+// строк в tasks, они удаляются вместе с изображением. Остальное — сироты: результаты
+// отменённых задач, прежних версий пресетов и наборов версий.
+type Collector struct {
+	images  ImageRepo
+	tasks   TaskRepo
+	storage storage.Storage
+	deleter ImageDeleter
+	opts    Opts
+	now     func() time.Time
+}
+
+func New(images ImageRepo, tasks TaskRepo, storage storage.Storage, deleter ImageDeleter, opts Opts) *Collector {
+	if opts.GracePeriod <= 0 {
+		opts.GracePeriod = defaultGracePeriod
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	return &Collector{
+		images:  images,
+		tasks:   tasks,
+		storage: storage,
+		deleter: deleter,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// completedResult — основной результат завершённой задачи, проверяется на наличие в хранилище.
+type completedResult struct {
+	taskID int
+	path   string
+}
+
+// Run выполняет один проход сборки мусора. В режиме DryRun ничего не удаляется.
+func (c *Collector) Run(ctx context.Context) (Report, error) {
+	const op = "gc.Run"
+
+	var report Report
+	cutoff := c.now().Add(-c.opts.GracePeriod)
+
+	// Сначала читается БД, потом хранилище: объект, загруженный между чтениями,
+	// окажется без строки, но его защитит GracePeriod.
+	// Из строк в памяти остаются только пути: id изображения → путь оригинала и пути результатов
+	live := make(map[uuid.UUID]string)
+	stalled := make(map[uuid.UUID]struct{})
+	for after := uuid.Nil; ; {
+		images, err := c.images.ListImagesPage(ctx, after, c.opts.PageSize)
+		if err != nil {
+			return Report{}, errutils.Wrap(op, err)
+		}
+		for _, image := range images {
+			switch {
+			case image.DeletedAt == nil:
+				live[image.ID] = image.Path
+			case image.DeletedAt.Before(cutoff):
+				stalled[image.ID] = struct{}{}
+				report.StalledDeletes = append(report.StalledDeletes, image.ID)
+			}
+		}
+		if len(images) < c.opts.PageSize {
+			break
+		}
+		after = images[len(images)-1].ID
+	}
+
+	referenced := make(map[string]struct{})
+	var completed []completedResult
+	for afterID := 0; ; {
+		tasks, err := c.tasks.ListTasksPage(ctx, afterID, c.opts.PageSize)
+		if err != nil {
+			return Report{}, errutils.Wrap(op, err)
+		}
+		for _, task := range tasks {
+			if _, ok := live[task.ImageID]; !ok {
+				continue
+			}
+			switch task.Status {
+			case domain.Failed, domain.Canceled:
+				// Результата нет или он отброшен: оставшиеся объекты — сироты
+				continue
+			case domain.Completed:
+				completed = append(completed, completedResult{taskID: task.ID, path: task.ProcessedPath})
+			}
+			for _, p := range resultPaths(task) {
+				referenced[p] = struct{}{}
+			}
+		}
+		if len(tasks) < c.opts.PageSize {
+			break
+		}
+		afterID = tasks[len(tasks)-1].ID
+	}
+
+	originals, err := c.storage.List(ctx, originalPrefix)
+	if err != nil {
+		return Report{}, errutils.Wrap(op, err)
+	}
+	processed, err := c.storage.List(ctx, processedPrefix)
+	if err != nil {
+		return Report{}, errutils.Wrap(op, err)
+	}
+
+	stored := make(map[string]struct{}, len(originals)+len(processed))
+	for _, obj := range append(originals, processed...) {
+		stored[obj.Name] = struct{}{}
+
+		ID := imageIDFromName(obj.Name)
+		if originalPath, ok := live[ID]; ok && isReferenced(obj.Name, originalPath, referenced) {
+			continue
+		}
+		// Объекты изображения с прерванным удалением удалит само повторное удаление
+		if _, ok := stalled[ID]; ok {
+			continue
+		}
+
+		if obj.LastModified.After(cutoff) {
+			report.Recent++
+			continue
+		}
+		report.Orphans = append(report.Orphans, obj)
+	}
+
+	for ID, originalPath := range live {
+		if _, ok := stored[originalPath]; !ok {
+			report.MissingOriginals = append(report.MissingOriginals, ID)
+		}
+	}
+	for _, result := range completed {
+		if _, ok := stored[result.path]; !ok {
+			report.MissingResults = append(report.MissingResults, result.taskID)
+		}
+	}
+
+	if c.opts.DryRun {
+		return report, nil
+	}
+
+	for _, ID := range report.StalledDeletes {
+		if err := c.deleter.DeleteImage(ctx, ID); err != nil {
+			zlog.Logger.Error().Err(err).Str("image_id", ID.String()).Msg("failed to finish image deletion")
+		}
+	}
+	for _, obj := range report.Orphans {
+		if err := c.storage.Delete(ctx, obj.Name); err != nil {
+			zlog.Logger.Error().Err(err).Str("object", obj.Name).Msg("failed to delete orphaned object")
+			continue
+		}
+		report.Deleted++
+	}
+
+	return report, nil
+}
+
+// isReferenced сообщает, нужен ли объект живого изображения с оригиналом originalPath.
+func isReferenced(name, originalPath string, referenced map[string]struct{}) bool {
+	if name == originalPath || transform.IsDerivativePath(name) {
+		return true
+	}
+	_, ok := referenced[name]
+	return ok
+}
+
+// resultPaths возвращает пути объектов, которые сохраняет задача: результат,
+// а для variants ещё и все версии набора.
+func resultPaths(task domain.Task) []string {
+	paths := []string{task.ProcessedPath}
+	if task.Type != domain.Variants || task.Params.Variants == nil {
+		return paths
+	}
+	for _, width := range task.Params.Variants.Widths {
+		for _, output := range task.Params.Variants.Outputs {
+			paths = append(paths, utils.VariantPath(task.ProcessedPath, width, output.Format.Ext()))
+		}
+	}
+	return paths
+}
+
+// imageIDFromName извлекает UUID изображения из имени объекта.
+// Например: original/uuid.jpg, processed/uuid_resize_640x480.jpg → uuid.
+// Для имён другого вида возвращает uuid.Nil.
+func imageIDFromName(name string) uuid.UUID {
+	base := path.Base(name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+	base, _, _ = strings.Cut(base, "_")
+
+	ID, err := uuid.Parse(base)
+	if err != nil {
+		return uuid.Nil
+	}
+	return ID
+}
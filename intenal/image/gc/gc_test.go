@@ -0,0 +1,123 @@
+package gc
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ilam072/image-processor/intenal/storage/memory"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeImages []domain.Image
+
+func (f fakeImages) ListImagesPage(_ context.Context, after uuid.UUID, limit int) ([]domain.Image, error) {
+	var page []domain.Image
+	for _, image := range f {
+		if strings.Compare(image.ID.String(), after.String()) > 0 && len(page) < limit {
+			page = append(page, image)
+		}
+	}
+	return page, nil
+}
+
+type fakeTasks []domain.Task
+
+func (f fakeTasks) ListTasksPage(_ context.Context, afterID int, limit int) ([]domain.Task, error) {
+	var page []domain.Task
+	for _, task := range f {
+		if task.ID > afterID && len(page) < limit {
+			page = append(page, task)
+		}
+	}
+	return page, nil
+}
+
+type noDeleter struct{}
+
+func (noDeleter) DeleteImage(context.Context, uuid.UUID) error { return nil }
+
+func TestRunReconcilesProcessedObjectsWithTasks(t *testing.T) {
+	ctx := context.Background()
+	ID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	original := "original/" + ID.String() + ".jpg"
+	prefix := "processed/" + ID.String()
+
+	images := fakeImages{{ID: ID, Path: original}}
+	tasks := fakeTasks{
+		{ID: 1, ImageID: ID, Type: domain.Resize, Status: domain.Completed, ProcessedPath: prefix + "_resize.jpg"},
+		{ID: 2, ImageID: ID, Type: domain.Variants, Status: domain.Completed, ProcessedPath: prefix + "_variants_product.json",
+			Params: domain.TaskParams{Variants: &domain.VariantsParams{
+				Widths:  []int{320, 640},
+				Outputs: []domain.EncodeParams{{Format: domain.FormatJPEG}},
+			}}},
+		{ID: 3, ImageID: ID, Type: domain.Rotate, Status: domain.Processing, ProcessedPath: prefix + "_rotate.jpg"},
+		{ID: 4, ImageID: ID, Type: domain.Flip, Status: domain.Canceled, ProcessedPath: prefix + "_flip.jpg"},
+		{ID: 5, ImageID: ID, Type: domain.Crop, Status: domain.Completed, ProcessedPath: prefix + "_crop.jpg"},
+	}
+
+	s := memory.New()
+	keep := []string{
+		original,
+		prefix + "_resize.jpg",
+		prefix + "_variants_product.json",
+		prefix + "_variants_product_320w.jpg",
+		prefix + "_variants_product_640w.jpg",
+		prefix + "_rotate.jpg",
+		prefix + "_t_0123456789ab.webp",
+	}
+	orphans := []string{
+		prefix + "_flip.jpg",
+		prefix + "_variants_product_1024w.jpg",
+		prefix + "_preset_card_0123456789ab.jpg",
+		"processed/00000000-0000-0000-0000-000000000002_resize.jpg",
+	}
+	for _, name := range append(slices.Clone(keep), orphans...) {
+		if err := s.Save(ctx, name, strings.NewReader("x"), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New(images, tasks, s, noDeleter{}, Opts{GracePeriod: time.Hour, DryRun: true, PageSize: 2})
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	report, err := c.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var got []string
+	for _, obj := range report.Orphans {
+		got = append(got, obj.Name)
+	}
+	slices.Sort(got)
+	slices.Sort(orphans)
+	if !slices.Equal(got, orphans) {
+		t.Errorf("orphans = %v, want %v", got, orphans)
+	}
+	if !slices.Equal(report.MissingResults, []int{5}) {
+		t.Errorf("missing results = %v, want [5]", report.MissingResults)
+	}
+	if len(report.MissingOriginals) != 0 || report.Deleted != 0 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestRunKeepsRecentObjects(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	if err := s.Save(ctx, "processed/00000000-0000-0000-0000-000000000002_resize.jpg", strings.NewReader("x"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(fakeImages{}, fakeTasks{}, s, noDeleter{}, Opts{GracePeriod: time.Hour})
+	report, err := c.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Recent != 1 || len(report.Orphans) != 0 || report.Deleted != 0 {
+		t.Errorf("report = %+v, want one recent object and nothing deleted", report)
+	}
+}
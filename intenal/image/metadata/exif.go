@@ -0,0 +1,284 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Теги EXIF, которые нам нужны
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+)
+
+// Размеры типов значений TIFF в байтах
+var typeSizes = map[uint16]int{
+	1:  1, // BYTE
+	2:  1, // ASCII
+	3:  2, // SHORT
+	4:  4, // LONG
+	5:  8, // RATIONAL
+	6:  1, // SBYTE
+	7:  1, // UNDEFINED
+	8:  2, // SSHORT
+	9:  4, // SLONG
+	10: 8, // SRATIONAL
+	11: 4, // FLOAT
+	12: 8, // DOUBLE
+}
+
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+var ErrInvalidEXIF = errors.New("invalid exif data")
+
+// EXIF — выбранные поля EXIF.
+type EXIF struct {
+	Make        string
+	Model       string
+	Orientation int
+	CapturedAt  *time.Time
+}
+
+// tiff — EXIF-блок в формате TIFF (заголовок, IFD и данные).
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type entry struct {
+	pos   int // смещение записи в data
+	tag   uint16
+	typ   uint16
+	count uint32
+}
+
+func newTIFF(data []byte) (*tiff, error) {
+	if len(data) < 8 {
+		return nil, ErrInvalidEXIF
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, ErrInvalidEXIF
+	}
+
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, ErrInvalidEXIF
+	}
+
+	return &tiff{data: data, order: order}, nil
+}
+
+func (t *tiff) firstIFD() uint32 {
+	return t.order.Uint32(t.data[4:8])
+}
+
+// ifd читает записи каталога по смещению.
+func (t *tiff) ifd(offset uint32) ([]entry, error) {
+	start := int(offset)
+	if offset == 0 || start+2 > len(t.data) {
+		return nil, fmt.Errorf("%w: ifd offset %d out of range", ErrInvalidEXIF, offset)
+	}
+
+	n := int(t.order.Uint16(t.data[start : start+2]))
+	if start+2+n*12 > len(t.data) {
+		return nil, fmt.Errorf("%w: ifd at %d is truncated", ErrInvalidEXIF, offset)
+	}
+
+	entries := make([]entry, 0, n)
+	for i := 0; i < n; i++ {
+		pos := start + 2 + i*12
+		entries = append(entries, entry{
+			pos:   pos,
+			tag:   t.order.Uint16(t.data[pos : pos+2]),
+			typ:   t.order.Uint16(t.data[pos+2 : pos+4]),
+			count: t.order.Uint32(t.data[pos+4 : pos+8]),
+		})
+	}
+
+	return entries, nil
+}
+
+// value возвращает байты значения записи: до 4 байт хранятся в самой записи, иначе по смещению.
+func (t *tiff) value(e entry) ([]byte, bool) {
+	size, ok := typeSizes[e.typ]
+	if !ok {
+		return nil, false
+	}
+
+	total := uint64(size) * uint64(e.count)
+	if total <= 4 {
+		return t.data[e.pos+8 : e.pos+8+int(total)], true
+	}
+
+	offset := uint64(t.order.Uint32(t.data[e.pos+8 : e.pos+12]))
+	if offset+total > uint64(len(t.data)) {
+		return nil, false
+	}
+
+	return t.data[offset : offset+total], true
+}
+
+func (t *tiff) uint(e entry) (uint32, bool) {
+	v, ok := t.value(e)
+	if !ok || e.count == 0 {
+		return 0, false
+	}
+
+	switch e.typ {
+	case 3:
+		return uint32(t.order.Uint16(v)), true
+	case 4:
+		return t.order.Uint32(v), true
+	default:
+		return 0, false
+	}
+}
+
+func (t *tiff) string(e entry) string {
+	v, ok := t.value(e)
+	if !ok || e.typ != 2 {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimRight(string(v), "\x00"))
+}
+
+// ParseEXIF разбирает EXIF-блок (TIFF без префикса "Exif\0\0").
+func ParseEXIF(data []byte) (EXIF, error) {
+	t, err := newTIFF(data)
+	if err != nil {
+		return EXIF{}, err
+	}
+
+	entries, err := t.ifd(t.firstIFD())
+	if err != nil {
+		return EXIF{}, err
+	}
+
+	var (
+		result   EXIF
+		dateTime string
+		exifIFD  uint32
+	)
+	for _, e := range entries {
+		switch e.tag {
+		case tagMake:
+			result.Make = t.string(e)
+		case tagModel:
+			result.Model = t.string(e)
+		case tagOrientation:
+			if v, ok := t.uint(e); ok && v >= 1 && v <= 8 {
+				result.Orientation = int(v)
+			}
+		case tagDateTime:
+			dateTime = t.string(e)
+		case tagExifIFD:
+			exifIFD, _ = t.uint(e)
+		}
+	}
+
+	// Время съёмки лежит в Exif IFD, DateTime из IFD0 — запасной вариант
+	if exifIFD != 0 {
+		if sub, err := t.ifd(exifIFD); err == nil {
+			for _, e := range sub {
+				if e.tag == tagDateTimeOriginal {
+					if original := t.string(e); original != "" {
+						dateTime = original
+					}
+				}
+			}
+		}
+	}
+
+	if captured, err := time.Parse(exifTimeLayout, dateTime); err == nil {
+		result.CapturedAt = &captured
+	}
+
+	return result, nil
+}
+
+// StripGPS возвращает копию EXIF без GPS: записи GPS IFD и их данные затираются нулями,
+// ссылка на GPS IFD удаляется из IFD0. Размер блока и смещения остальных данных не меняются.
+func StripGPS(data []byte) ([]byte, error) {
+	t, err := newTIFF(append([]byte(nil), data...))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := t.ifd(t.firstIFD())
+	if err != nil {
+		return nil, err
+	}
+
+	ifdStart := int(t.firstIFD())
+	for _, e := range entries {
+		if e.tag != tagGPSIFD {
+			continue
+		}
+
+		offset, ok := t.uint(e)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid gps ifd pointer", ErrInvalidEXIF)
+		}
+
+		gps, err := t.ifd(offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range gps {
+			if v, ok := t.value(g); ok {
+				clear(v)
+			}
+		}
+		clear(t.data[offset : int(offset)+2+len(gps)*12])
+
+		// Удаляем запись-ссылку из IFD0: сдвигаем следующие записи и указатель
+		// на следующий IFD на одну запись вверх. Смещения данных абсолютные и не меняются.
+		end := ifdStart + 2 + len(entries)*12 + 4
+		if end > len(t.data) {
+			return nil, fmt.Errorf("%w: ifd0 is truncated", ErrInvalidEXIF)
+		}
+		copy(t.data[e.pos:end-12], t.data[e.pos+12:end])
+		clear(t.data[end-12 : end])
+		t.order.PutUint16(t.data[ifdStart:], uint16(len(entries)-1))
+		break
+	}
+
+	return t.data, nil
+}
+
+// ResetOrientation возвращает копию EXIF с Orientation = 1. Используется после того,
+// как поворот уже применён к пикселям, чтобы просмотрщики не повернули картинку повторно.
+func ResetOrientation(data []byte) ([]byte, error) {
+	t, err := newTIFF(append([]byte(nil), data...))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := t.ifd(t.firstIFD())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.tag == tagOrientation && e.typ == 3 && e.count == 1 {
+			t.order.PutUint16(t.data[e.pos+8:], 1)
+		}
+	}
+
+	return t.data, nil
+}
@@ -0,0 +1,128 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	markerSOS  = 0xda
+	markerAPP1 = 0xe1
+	markerAPP2 = 0xe2
+
+	// Максимальная длина данных сегмента: 0xffff минус 2 байта длины
+	maxSegmentPayload = 0xffff - 2
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// extractJPEG проходит по сегментам до начала данных скана (SOS).
+func extractJPEG(data []byte) Metadata {
+	var (
+		md  Metadata
+		icc = map[byte][]byte{}
+	)
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			break
+		}
+
+		marker := data[pos+1]
+		if marker == 0xff {
+			pos++ // заполняющие байты
+			continue
+		}
+		if marker >= 0xd0 && marker <= 0xd7 || marker == 0x01 {
+			pos += 2 // маркеры без длины
+			continue
+		}
+		if marker == markerSOS {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			break
+		}
+		payload := data[pos+4 : pos+2+length]
+
+		switch {
+		case marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader) && md.EXIF == nil:
+			md.EXIF = clone(payload[len(exifHeader):])
+		case marker == markerAPP1 && bytes.HasPrefix(payload, xmpHeader) && md.XMP == nil:
+			md.XMP = clone(payload[len(xmpHeader):])
+		case marker == markerAPP2 && bytes.HasPrefix(payload, iccHeader) && len(payload) > len(iccHeader)+2:
+			// ICC-профиль может быть разбит на несколько сегментов: номер части и их количество
+			seq := payload[len(iccHeader)]
+			icc[seq] = payload[len(iccHeader)+2:]
+		}
+
+		pos += 2 + length
+	}
+
+	if len(icc) > 0 {
+		seqs := make([]int, 0, len(icc))
+		for seq := range icc {
+			seqs = append(seqs, int(seq))
+		}
+		sort.Ints(seqs)
+		for _, seq := range seqs {
+			md.ICC = append(md.ICC, icc[byte(seq)]...)
+		}
+	}
+
+	return md
+}
+
+// embedJPEG вставляет сегменты APP1 (EXIF, XMP) и APP2 (ICC) сразу после SOI.
+func embedJPEG(encoded []byte, md Metadata) ([]byte, error) {
+	if !bytes.HasPrefix(encoded, jpegSignature) {
+		return nil, errNotFormat("jpeg")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(jpegSignature)
+
+	if len(md.EXIF) > 0 && len(exifHeader)+len(md.EXIF) <= maxSegmentPayload {
+		writeSegment(&buf, markerAPP1, exifHeader, md.EXIF)
+	}
+	if len(md.XMP) > 0 && len(xmpHeader)+len(md.XMP) <= maxSegmentPayload {
+		writeSegment(&buf, markerAPP1, xmpHeader, md.XMP)
+	}
+	if len(md.ICC) > 0 {
+		chunkSize := maxSegmentPayload - len(iccHeader) - 2
+		total := (len(md.ICC) + chunkSize - 1) / chunkSize
+		if total <= 255 {
+			for i := 0; i < total; i++ {
+				chunk := md.ICC[i*chunkSize : min((i+1)*chunkSize, len(md.ICC))]
+				header := append(append([]byte(nil), iccHeader...), byte(i+1), byte(total))
+				writeSegment(&buf, markerAPP2, header, chunk)
+			}
+		}
+	}
+
+	buf.Write(encoded[len(jpegSignature):])
+
+	return buf.Bytes(), nil
+}
+
+func writeSegment(buf *bytes.Buffer, marker byte, header, payload []byte) {
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(2+len(header)+len(payload)))
+
+	buf.Write([]byte{0xff, marker})
+	buf.Write(length[:])
+	buf.Write(header)
+	buf.Write(payload)
+}
+
+func clone(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,73 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+)
+
+// Metadata — метаданные изображения, которые не переживают перекодирование сами по себе.
+type Metadata struct {
+	EXIF []byte // TIFF-блок EXIF
+	ICC  []byte // ICC-профиль без сжатия
+	XMP  []byte // XMP-пакет
+}
+
+var (
+	jpegSignature = []byte{0xff, 0xd8}
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// Extract читает метаданные из JPEG или PNG. Для остальных форматов возвращает пустой результат.
+func Extract(data []byte) Metadata {
+	switch {
+	case bytes.HasPrefix(data, jpegSignature):
+		return extractJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return extractPNG(data)
+	default:
+		return Metadata{}
+	}
+}
+
+// Embed встраивает метаданные в закодированное изображение. Форматы кроме JPEG и PNG
+// метаданные не поддерживают — для них изображение возвращается без изменений.
+func Embed(encoded []byte, format domain.ImageFormat, md Metadata) ([]byte, error) {
+	if len(md.EXIF) == 0 && len(md.ICC) == 0 && len(md.XMP) == 0 {
+		return encoded, nil
+	}
+
+	switch format {
+	case domain.FormatJPEG:
+		return embedJPEG(encoded, md)
+	case domain.FormatPNG:
+		return embedPNG(encoded, md)
+	default:
+		return encoded, nil
+	}
+}
+
+// Sanitize готовит метаданные оригинала к записи в результат: сбрасывает Orientation
+// (поворот уже применён к пикселям) и, если keepGPS = false, удаляет GPS.
+// Повреждённый EXIF отбрасывается целиком, чтобы не унести координаты случайно.
+func Sanitize(md Metadata, keepGPS bool) Metadata {
+	if len(md.EXIF) == 0 {
+		return md
+	}
+
+	exif, err := ResetOrientation(md.EXIF)
+	if err == nil && !keepGPS {
+		exif, err = StripGPS(exif)
+	}
+	if err != nil {
+		md.EXIF = nil
+		return md
+	}
+
+	md.EXIF = exif
+	return md
+}
+
+func errNotFormat(format string) error {
+	return fmt.Errorf("encoded data is not a valid %s image", format)
+}
@@ -0,0 +1,165 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	chunkIHDR = "IHDR"
+	chunkIEND = "IEND"
+	chunkEXIF = "eXIf"
+	chunkICCP = "iCCP"
+	chunkITXT = "iTXt"
+
+	xmpKeyword = "XML:com.adobe.xmp"
+	iccName    = "ICC profile"
+
+	// Предел распаковки ICC-профиля, чтобы не раздувать память на подложенных данных
+	maxICCSize = 4 << 20
+)
+
+// extractPNG проходит по чанкам до IEND.
+func extractPNG(data []byte) Metadata {
+	var md Metadata
+
+	pos := len(pngSignature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		typ := string(data[pos+4 : pos+8])
+		if length < 0 || pos+12+length > len(data) {
+			break
+		}
+		payload := data[pos+8 : pos+8+length]
+
+		switch typ {
+		case chunkEXIF:
+			md.EXIF = clone(payload)
+		case chunkICCP:
+			if icc, ok := parseICCP(payload); ok {
+				md.ICC = icc
+			}
+		case chunkITXT:
+			if xmp, ok := parseXMP(payload); ok {
+				md.XMP = xmp
+			}
+		case chunkIEND:
+			return md
+		}
+
+		pos += 12 + length
+	}
+
+	return md
+}
+
+// parseICCP: имя профиля, \0, метод сжатия (0 — zlib), сжатый профиль.
+func parseICCP(payload []byte) ([]byte, bool) {
+	name, rest, ok := bytes.Cut(payload, []byte{0})
+	if !ok || len(name) == 0 || len(rest) < 1 || rest[0] != 0 {
+		return nil, false
+	}
+
+	icc, err := inflate(rest[1:])
+	if err != nil {
+		return nil, false
+	}
+
+	return icc, true
+}
+
+// parseXMP: ключевое слово, \0, флаг сжатия, метод сжатия, язык, \0, перевод ключа, \0, текст.
+func parseXMP(payload []byte) ([]byte, bool) {
+	keyword, rest, ok := bytes.Cut(payload, []byte{0})
+	if !ok || string(keyword) != xmpKeyword || len(rest) < 2 {
+		return nil, false
+	}
+
+	compressed := rest[0] == 1
+	_, rest, ok = bytes.Cut(rest[2:], []byte{0})
+	if !ok {
+		return nil, false
+	}
+	_, text, ok := bytes.Cut(rest, []byte{0})
+	if !ok {
+		return nil, false
+	}
+
+	if compressed {
+		xmp, err := inflate(text)
+		if err != nil {
+			return nil, false
+		}
+		return xmp, true
+	}
+
+	return clone(text), true
+}
+
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(io.LimitReader(r, maxICCSize))
+}
+
+// embedPNG вставляет чанки iCCP, eXIf и iTXt сразу после IHDR — до PLTE и IDAT, как требует спецификация.
+func embedPNG(encoded []byte, md Metadata) ([]byte, error) {
+	ihdrEnd := len(pngSignature) + 12 + 13
+	if !bytes.HasPrefix(encoded, pngSignature) || len(encoded) < ihdrEnd ||
+		string(encoded[len(pngSignature)+4:len(pngSignature)+8]) != chunkIHDR {
+		return nil, errNotFormat("png")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(encoded[:ihdrEnd])
+
+	if len(md.ICC) > 0 {
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		if _, err := w.Write(md.ICC); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		payload := append([]byte(iccName), 0, 0)
+		writeChunk(&buf, chunkICCP, append(payload, compressed.Bytes()...))
+	}
+	if len(md.EXIF) > 0 {
+		writeChunk(&buf, chunkEXIF, md.EXIF)
+	}
+	if len(md.XMP) > 0 {
+		// Без сжатия, пустые язык и перевод ключевого слова
+		payload := append([]byte(xmpKeyword), 0, 0, 0, 0, 0)
+		writeChunk(&buf, chunkITXT, append(payload, md.XMP...))
+	}
+
+	buf.Write(encoded[ihdrEnd:])
+
+	return buf.Bytes(), nil
+}
+
+func writeChunk(buf *bytes.Buffer, typ string, payload []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(payload)))
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(payload)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+
+	buf.Write(length[:])
+	buf.WriteString(typ)
+	buf.Write(payload)
+	buf.Write(sum[:])
+}
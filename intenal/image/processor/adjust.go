@@ -0,0 +1,198 @@
+package processor
+
+import (
+	"github.com/disintegration/imaging"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// adjust применяет цветокоррекцию в фиксированном порядке: тон, насыщенность, гамма, яркость, контраст.
+func adjust(img image.Image, params domain.AdjustParams) image.Image {
+	if params.Hue != 0 {
+		img = shiftHue(img, params.Hue)
+	}
+	if params.Saturation != 0 {
+		img = imaging.AdjustSaturation(img, params.Saturation)
+	}
+	if params.Gamma != 0 {
+		img = imaging.AdjustGamma(img, params.Gamma)
+	}
+	if params.Brightness != 0 {
+		img = imaging.AdjustBrightness(img, params.Brightness)
+	}
+	if params.Contrast != 0 {
+		img = imaging.AdjustContrast(img, params.Contrast)
+	}
+	return img
+}
+
+// shiftHue поворачивает тон каждого пикселя на degrees в пространстве HSL.
+func shiftHue(img image.Image, degrees float64) image.Image {
+	shift := degrees / 360
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		h, s, l := rgbToHSL(c)
+		h = math.Mod(h+shift+1, 1)
+		r, g, b := hslToRGB(h, s, l)
+		return color.NRGBA{R: r, G: g, B: b, A: c.A}
+	})
+}
+
+// sepia — классическая матрица сепии поверх исходных цветов.
+func sepia(img image.Image) image.Image {
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		r, g, b := float64(c.R), float64(c.G), float64(c.B)
+		return color.NRGBA{
+			R: clamp(0.393*r + 0.769*g + 0.189*b),
+			G: clamp(0.349*r + 0.686*g + 0.168*b),
+			B: clamp(0.272*r + 0.534*g + 0.131*b),
+			A: c.A,
+		}
+	})
+}
+
+// sharpen — нерезкое маскирование с порогом: слабые перепады (шум) не усиливаются.
+func sharpen(img image.Image, params domain.SharpenParams) image.Image {
+	src := imaging.Clone(img)
+	blurred := imaging.Blur(src, params.Sigma)
+
+	dst := image.NewNRGBA(src.Bounds())
+	for i := 0; i < len(src.Pix); i += 4 {
+		s, b := src.Pix[i:i+4:i+4], blurred.Pix[i:i+4:i+4]
+
+		// Порог сравнивается по яркости, чтобы не менять оттенок на границе
+		diff := luma(s) - luma(b)
+		if math.Abs(diff) <= float64(params.Threshold) {
+			copy(dst.Pix[i:i+4], s)
+			continue
+		}
+		for c := 0; c < 3; c++ {
+			dst.Pix[i+c] = clamp(float64(s[c]) + params.Amount*(float64(s[c])-float64(b[c])))
+		}
+		dst.Pix[i+3] = s[3]
+	}
+
+	return dst
+}
+
+// pixelate заменяет каждый блок BlockSize×BlockSize его средним цветом, во всём изображении
+// или только в Regions (координаты относительно левого верхнего угла).
+func pixelate(img image.Image, params domain.PixelateParams) image.Image {
+	dst := imaging.Clone(img)
+	bounds := dst.Bounds()
+
+	regions := []image.Rectangle{bounds}
+	if len(params.Regions) > 0 {
+		regions = regions[:0]
+		for _, r := range params.Regions {
+			rect := image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height).Add(bounds.Min).Intersect(bounds)
+			if !rect.Empty() {
+				regions = append(regions, rect)
+			}
+		}
+	}
+
+	size := params.BlockSize
+	for _, region := range regions {
+		for y := region.Min.Y; y < region.Max.Y; y += size {
+			for x := region.Min.X; x < region.Max.X; x += size {
+				block := image.Rect(x, y, x+size, y+size).Intersect(region)
+				draw.Draw(dst, block, image.NewUniform(average(dst, block)), image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	return dst
+}
+
+func average(img *image.NRGBA, rect image.Rectangle) color.NRGBA {
+	var r, g, b, a, n int
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		i := img.PixOffset(rect.Min.X, y)
+		for x := rect.Min.X; x < rect.Max.X; x, i = x+1, i+4 {
+			r += int(img.Pix[i])
+			g += int(img.Pix[i+1])
+			b += int(img.Pix[i+2])
+			a += int(img.Pix[i+3])
+			n++
+		}
+	}
+	if n == 0 {
+		return color.NRGBA{}
+	}
+	return color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)}
+}
+
+func luma(p []uint8) float64 {
+	return 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+}
+
+func clamp(v float64) uint8 {
+	return uint8(math.Round(math.Min(math.Max(v, 0), 255)))
+}
+
+func rgbToHSL(c color.NRGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (hi + lo) / 2
+	if hi == lo {
+		return 0, 0, l
+	}
+
+	d := hi - lo
+	if l > 0.5 {
+		s = d / (2 - hi - lo)
+	} else {
+		s = d / (hi + lo)
+	}
+	switch hi {
+	case r:
+		h = (g - b) / d
+		if g < b {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h / 6, s, l
+}
+
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	if s == 0 {
+		v := clamp(l * 255)
+		return v, v, v
+	}
+
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+
+	return clamp(hueToRGB(p, q, h+1.0/3) * 255), clamp(hueToRGB(p, q, h) * 255), clamp(hueToRGB(p, q, h-1.0/3) * 255)
+}
+
+func hueToRGB(p, q, t float64) float64 {
+	if t < 0 {
+		t++
+	}
+	if t > 1 {
+		t--
+	}
+	switch {
+	case t < 1.0/6:
+		return p + (q-p)*6*t
+	case t < 1.0/2:
+		return q
+	case t < 2.0/3:
+		return p + (q-p)*(2.0/3-t)*6
+	default:
+		return p
+	}
+}
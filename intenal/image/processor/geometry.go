@@ -0,0 +1,138 @@
+package processor
+
+import (
+	"github.com/disintegration/imaging"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Smart crop ищет область на уменьшенной копии: для оценки границ полное разрешение не нужно
+const smartCropAnalysisSize = 256
+
+func aspectCrop(img image.Image, params domain.AspectCropParams) (image.Image, error) {
+	aw, ah, err := domain.ParseAspect(params.Aspect)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor, ok := anchors[params.Anchor]
+	if !ok {
+		anchor = imaging.Center
+	}
+
+	w, h := aspectSize(img.Bounds().Dx(), img.Bounds().Dy(), aw, ah)
+	return imaging.CropAnchor(img, w, h, anchor), nil
+}
+
+func rotate(img image.Image, params domain.RotateParams) (image.Image, error) {
+	var bg color.Color = color.Transparent
+	if params.Background != domain.BackgroundTransparent {
+		c, err := parseColor(params.Background)
+		if err != nil {
+			return nil, err
+		}
+		bg = c
+	}
+
+	return imaging.Rotate(img, params.Angle, bg), nil
+}
+
+func flip(img image.Image, params domain.FlipParams) image.Image {
+	switch params.Direction {
+	case domain.FlipVertical:
+		return imaging.FlipV(img)
+	case domain.FlipBoth:
+		return imaging.Rotate180(img)
+	default:
+		return imaging.FlipH(img)
+	}
+}
+
+// smartCrop вырезает наибольшую область с соотношением сторон Width:Height, в которой
+// суммарная энергия границ (градиент яркости) максимальна, и приводит её к Width×Height.
+func smartCrop(img image.Image, params domain.SmartCropParams) image.Image {
+	region := imaging.Crop(img, smartCropRect(img, params))
+	return imaging.Resize(region, params.Width, params.Height, imaging.Lanczos)
+}
+
+// smartCropRect выбирает область для smartCrop в координатах img.
+func smartCropRect(img image.Image, params domain.SmartCropParams) image.Rectangle {
+	bounds := img.Bounds()
+	cw, ch := aspectSize(bounds.Dx(), bounds.Dy(), params.Width, params.Height)
+
+	small := img
+	if max(bounds.Dx(), bounds.Dy()) > smartCropAnalysisSize {
+		small = imaging.Fit(img, smartCropAnalysisSize, smartCropAnalysisSize, imaging.Box)
+	}
+	sw, sh := small.Bounds().Dx(), small.Bounds().Dy()
+	scaleX := float64(sw) / float64(bounds.Dx())
+	scaleY := float64(sh) / float64(bounds.Dy())
+
+	scw := min(max(int(math.Round(float64(cw)*scaleX)), 1), sw)
+	sch := min(max(int(math.Round(float64(ch)*scaleY)), 1), sh)
+
+	sum := edgeIntegral(small)
+	window := func(x, y int) int64 {
+		stride := sw + 1
+		return sum[(y+sch)*stride+x+scw] - sum[y*stride+x+scw] - sum[(y+sch)*stride+x] + sum[y*stride+x]
+	}
+
+	// Позиция меняется только на строго лучшую, так что при равной энергии (например, однотонное
+	// изображение) остаётся центр
+	bestX, bestY := (sw-scw)/2, (sh-sch)/2
+	best := window(bestX, bestY)
+	for y := 0; y <= sh-sch; y++ {
+		for x := 0; x <= sw-scw; x++ {
+			if e := window(x, y); e > best {
+				best, bestX, bestY = e, x, y
+			}
+		}
+	}
+
+	x := min(int(math.Round(float64(bestX)/scaleX)), bounds.Dx()-cw)
+	y := min(int(math.Round(float64(bestY)/scaleY)), bounds.Dy()-ch)
+	return image.Rect(x, y, x+cw, y+ch).Add(bounds.Min)
+}
+
+// edgeIntegral считает модуль градиента яркости в каждой точке и возвращает
+// интегральное изображение размером (w+1)×(h+1) для быстрых сумм по окнам.
+func edgeIntegral(img image.Image) []int64 {
+	gray := imaging.Grayscale(img)
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	lum := func(x, y int) int {
+		x = min(max(x, 0), w-1)
+		y = min(max(y, 0), h-1)
+		return int(gray.Pix[y*gray.Stride+x*4])
+	}
+
+	stride := w + 1
+	sum := make([]int64, stride*(h+1))
+	for y := 0; y < h; y++ {
+		var row int64
+		for x := 0; x < w; x++ {
+			dx := lum(x+1, y) - lum(x-1, y)
+			dy := lum(x, y+1) - lum(x, y-1)
+			row += int64(abs(dx) + abs(dy))
+			sum[(y+1)*stride+x+1] = sum[y*stride+x+1] + row
+		}
+	}
+
+	return sum
+}
+
+// aspectSize возвращает наибольший размер с соотношением сторон aw:ah, помещающийся в w×h.
+func aspectSize(w, h, aw, ah int) (int, int) {
+	if w*ah >= h*aw {
+		return max(h*aw/ah, 1), h
+	}
+	return w, max(w*ah/aw, 1)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// flatWithSquare возвращает серое изображение w×h с белым квадратом square.
+func flatWithSquare(w, h int, square image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.NRGBA{R: 128, G: 128, B: 128, A: 255}), image.Point{}, draw.Src)
+	draw.Draw(img, square, image.NewUniform(color.White), image.Point{}, draw.Src)
+	return img
+}
+
+func TestSmartCropRect(t *testing.T) {
+	tests := []struct {
+		name   string
+		img    *image.NRGBA
+		params domain.SmartCropParams
+		square image.Rectangle // должен целиком попасть в область
+		size   image.Point
+	}{
+		{
+			name:   "square on the right",
+			img:    flatWithSquare(200, 100, image.Rect(150, 40, 170, 60)),
+			params: domain.SmartCropParams{Width: 50, Height: 50},
+			square: image.Rect(150, 40, 170, 60),
+			size:   image.Pt(100, 100),
+		},
+		{
+			name:   "square on the left",
+			img:    flatWithSquare(200, 100, image.Rect(10, 40, 30, 60)),
+			params: domain.SmartCropParams{Width: 50, Height: 50},
+			square: image.Rect(10, 40, 30, 60),
+			size:   image.Pt(100, 100),
+		},
+		{
+			name:   "square at the top of a tall image",
+			img:    flatWithSquare(100, 300, image.Rect(40, 10, 60, 30)),
+			params: domain.SmartCropParams{Width: 100, Height: 100},
+			square: image.Rect(40, 10, 60, 30),
+			size:   image.Pt(100, 100),
+		},
+		{
+			name:   "downscaled analysis with tall window",
+			img:    flatWithSquare(1000, 500, image.Rect(800, 200, 900, 300)),
+			params: domain.SmartCropParams{Width: 100, Height: 200},
+			square: image.Rect(800, 200, 900, 300),
+			size:   image.Pt(250, 500),
+		},
+		{
+			name:   "downscaled analysis with square window",
+			img:    flatWithSquare(1000, 500, image.Rect(800, 200, 900, 300)),
+			params: domain.SmartCropParams{Width: 1, Height: 1},
+			square: image.Rect(800, 200, 900, 300),
+			size:   image.Pt(500, 500),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := smartCropRect(tt.img, tt.params)
+			if got.Size() != tt.size {
+				t.Fatalf("smartCropRect() = %v, want size %v", got, tt.size)
+			}
+			if !got.In(tt.img.Bounds()) {
+				t.Fatalf("smartCropRect() = %v, out of image bounds %v", got, tt.img.Bounds())
+			}
+			if !tt.square.In(got) {
+				t.Fatalf("smartCropRect() = %v, want it to contain %v", got, tt.square)
+			}
+		})
+	}
+}
+
+func TestSmartCropRectFlatImage(t *testing.T) {
+	// На однотонном изображении энергия везде равна и остаётся центр
+	img := flatWithSquare(200, 100, image.Rectangle{})
+
+	got := smartCropRect(img, domain.SmartCropParams{Width: 1, Height: 1})
+	if want := image.Rect(50, 0, 150, 100); got != want {
+		t.Fatalf("smartCropRect() = %v, want %v", got, want)
+	}
+}
+
+func TestSmartCropRectOffsetBounds(t *testing.T) {
+	// Область возвращается в координатах изображения, даже если они начинаются не с нуля
+	img := flatWithSquare(200, 100, image.Rect(150, 40, 170, 60)).SubImage(image.Rect(100, 0, 200, 100))
+
+	got := smartCropRect(img, domain.SmartCropParams{Width: 1, Height: 2})
+	if !got.In(img.Bounds()) {
+		t.Fatalf("smartCropRect() = %v, out of image bounds %v", got, img.Bounds())
+	}
+	if square := image.Rect(150, 40, 170, 60); !square.In(got) {
+		t.Fatalf("smartCropRect() = %v, want it to contain %v", got, square)
+	}
+}
+
+func TestSmartCropSize(t *testing.T) {
+	img := flatWithSquare(200, 100, image.Rect(150, 40, 170, 60))
+
+	got := smartCrop(img, domain.SmartCropParams{Width: 40, Height: 30})
+	if size := got.Bounds().Size(); size != image.Pt(40, 30) {
+		t.Fatalf("smartCrop() size = %v, want 40x30", size)
+	}
+}
@@ -2,113 +2,254 @@ package processor
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/disintegration/imaging"
+	"github.com/ilam072/image-processor/intenal/image/metadata"
+	"github.com/ilam072/image-processor/intenal/types/domain"
 	"github.com/ilam072/image-processor/pkg/errutils"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
-	"golang.org/x/image/math/fixed"
 	"image"
-	"image/color"
-	"image/draw"
-	"image/jpeg"
+	"image/png"
 	"io"
 )
 
 type Opts struct {
 	Width  int
 	Height int
+
+	WatermarkText string // водяной знак по умолчанию, если в задаче не заданы текст и логотип
+	WatermarkLogo string // логотип по умолчанию вместо текста
+	FontsDir      string // каталог шрифтов TTF/OTF для водяных знаков
+	LogosDir      string // каталог PNG-логотипов для водяных знаков
 }
 
 type Processor struct {
-	opts Opts
+	opts   Opts
+	assets *assets
 }
 
 func NewProcessor(opts Opts) *Processor {
-	return &Processor{opts: opts}
+	return &Processor{opts: opts, assets: newAssets(opts.FontsDir, opts.LogosDir)}
 }
 
-func (p *Processor) Resize(img io.ReadCloser) (io.ReadCloser, error) {
-	decodedImage, _, err := image.Decode(img)
-	if err != nil {
-		return nil, errutils.Wrap("failed to decode image", err)
-	}
+var filters = map[string]imaging.ResampleFilter{
+	"nearest":    imaging.NearestNeighbor,
+	"box":        imaging.Box,
+	"linear":     imaging.Linear,
+	"hermite":    imaging.Hermite,
+	"mitchell":   imaging.MitchellNetravali,
+	"catmullrom": imaging.CatmullRom,
+	"bspline":    imaging.BSpline,
+	"gaussian":   imaging.Gaussian,
+	"lanczos":    imaging.Lanczos,
+}
 
-	resizedImage := imaging.Resize(decodedImage, p.opts.Width, p.opts.Height, imaging.Lanczos)
+var anchors = map[string]imaging.Anchor{
+	"center":       imaging.Center,
+	"top_left":     imaging.TopLeft,
+	"top":          imaging.Top,
+	"top_right":    imaging.TopRight,
+	"left":         imaging.Left,
+	"right":        imaging.Right,
+	"bottom_left":  imaging.BottomLeft,
+	"bottom":       imaging.Bottom,
+	"bottom_right": imaging.BottomRight,
+}
 
-	var buf bytes.Buffer
-	if err := imaging.Encode(&buf, resizedImage, imaging.JPEG); err != nil {
-		return nil, errutils.Wrap("failed to encode resized image", err)
-	}
+var formats = map[domain.ImageFormat]imaging.Format{
+	domain.FormatJPEG: imaging.JPEG,
+	domain.FormatPNG:  imaging.PNG,
+	domain.FormatGIF:  imaging.GIF,
+	domain.FormatBMP:  imaging.BMP,
+	domain.FormatTIFF: imaging.TIFF,
+}
 
-	// Возвращаем новый io.ReadCloser
-	return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+var pngCompression = map[domain.PNGCompression]png.CompressionLevel{
+	domain.PNGCompressionDefault: png.DefaultCompression,
+	domain.PNGCompressionNone:    png.NoCompression,
+	domain.PNGCompressionFast:    png.BestSpeed,
+	domain.PNGCompressionBest:    png.BestCompression,
 }
 
-func (p *Processor) Thumbnail(img io.ReadCloser) (io.ReadCloser, error) {
-	decodedImage, _, err := image.Decode(img)
+// Pipeline декодирует изображение один раз, поворачивает его по EXIF Orientation,
+// последовательно применяет операции и кодирует результат в формат завершающей
+// операции encode (по умолчанию JPEG). Метаданные оригинала по умолчанию не переносятся.
+func (p *Processor) Pipeline(img io.ReadCloser, ops []domain.Operation) (io.ReadCloser, error) {
+	data, err := io.ReadAll(img)
 	if err != nil {
-		return nil, errutils.Wrap("failed to decode image", err)
+		return nil, errutils.Wrap("failed to read image", err)
 	}
 
-	thumbnailImage := imaging.Thumbnail(decodedImage, p.opts.Width/10, p.opts.Height/10, imaging.Lanczos)
+	decodedImage, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, errutils.Wrap("failed to decode image", fmt.Errorf("%w: %w", domain.ErrUndecodableImage, err))
+	}
 
-	var buf bytes.Buffer
-	if err := imaging.Encode(&buf, thumbnailImage, imaging.JPEG); err != nil {
-		return nil, errutils.Wrap("failed to encode thumbnail image", err)
+	result := decodedImage
+	encodeParams := domain.EncodeParams{Format: domain.FormatJPEG, Quality: domain.DefaultJPEGQuality}
+	for _, op := range ops {
+		if op.Type == domain.OpEncode {
+			encodeParams = *op.Encode
+			continue
+		}
+
+		result, err = p.apply(result, op)
+		if err != nil {
+			return nil, errutils.Wrap(fmt.Sprintf("failed to apply %s", op.Type), err)
+		}
+	}
+
+	encoded, err := encode(result, encodeParams)
+	if err != nil {
+		return nil, err
 	}
 
-	return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	if encodeParams.Metadata == domain.MetadataPreserve {
+		md := metadata.Sanitize(metadata.Extract(data), encodeParams.KeepGPS)
+		encoded, err = metadata.Embed(encoded, encodeParams.Format, md)
+		if err != nil {
+			return nil, errutils.Wrap("failed to embed metadata", err)
+		}
+	}
+
+	return io.NopCloser(bytes.NewReader(encoded)), nil
 }
 
-func (p *Processor) Watermark(img io.ReadCloser) (io.ReadCloser, error) {
-	decodedImage, _, err := image.Decode(img)
-	if err != nil {
-		return nil, errutils.Wrap("failed to decode image", err)
+func (p *Processor) apply(img image.Image, op domain.Operation) (image.Image, error) {
+	switch op.Type {
+	case domain.OpResize:
+		if op.Resize == nil {
+			return nil, fmt.Errorf("resize params are missing")
+		}
+		return p.resize(img, *op.Resize), nil
+	case domain.OpCrop:
+		if op.Crop == nil {
+			return nil, fmt.Errorf("crop params are missing")
+		}
+		return crop(img, *op.Crop)
+	case domain.OpAspectCrop:
+		if op.AspectCrop == nil {
+			return nil, fmt.Errorf("aspect crop params are missing")
+		}
+		return aspectCrop(img, *op.AspectCrop)
+	case domain.OpSmartCrop:
+		if op.SmartCrop == nil {
+			return nil, fmt.Errorf("smart crop params are missing")
+		}
+		return smartCrop(img, *op.SmartCrop), nil
+	case domain.OpRotate:
+		if op.Rotate == nil {
+			return nil, fmt.Errorf("rotate params are missing")
+		}
+		return rotate(img, *op.Rotate)
+	case domain.OpFlip:
+		if op.Flip == nil {
+			return nil, fmt.Errorf("flip params are missing")
+		}
+		return flip(img, *op.Flip), nil
+	case domain.OpAdjust:
+		if op.Adjust == nil {
+			return nil, fmt.Errorf("adjust params are missing")
+		}
+		return adjust(img, *op.Adjust), nil
+	case domain.OpGrayscale:
+		return imaging.Grayscale(img), nil
+	case domain.OpSepia:
+		return sepia(img), nil
+	case domain.OpInvert:
+		return imaging.Invert(img), nil
+	case domain.OpBlur:
+		if op.Blur == nil {
+			return nil, fmt.Errorf("blur params are missing")
+		}
+		return imaging.Blur(img, op.Blur.Sigma), nil
+	case domain.OpSharpen:
+		if op.Sharpen == nil {
+			return nil, fmt.Errorf("sharpen params are missing")
+		}
+		return sharpen(img, *op.Sharpen), nil
+	case domain.OpPixelate:
+		if op.Pixelate == nil {
+			return nil, fmt.Errorf("pixelate params are missing")
+		}
+		return pixelate(img, *op.Pixelate), nil
+	case domain.OpThumbnail:
+		return imaging.Thumbnail(img, p.opts.Width/10, p.opts.Height/10, imaging.Lanczos), nil
+	case domain.OpWatermark:
+		params := domain.WatermarkParams{}
+		if op.Watermark != nil {
+			params = *op.Watermark
+		}
+		// Задачи, созданные до появления параметров, получают значения по умолчанию
+		params, err := domain.NewWatermarkParams(params)
+		if err != nil {
+			return nil, err
+		}
+		return p.watermark(img, params)
+	default:
+		return nil, fmt.Errorf("unexpected operation %q", op.Type)
 	}
+}
 
-	bounds := decodedImage.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+func (p *Processor) resize(img image.Image, params domain.ResizeParams) image.Image {
+	width, height := params.Width, params.Height
+	if width == 0 && height == 0 {
+		width, height = p.opts.Width, p.opts.Height
+	}
+
+	filter, ok := filters[params.Filter]
+	if !ok {
+		filter = imaging.Lanczos
+	}
 
-	// Копируем исходное изображение в RGBA
-	rgba := imaging.Clone(decodedImage)
+	anchor, ok := anchors[params.Anchor]
+	if !ok {
+		anchor = imaging.Center
+	}
 
-	// Текст watermark
-	watermarkText := "© MyBrand"
-	textColor := color.NRGBA{255, 255, 255, 220} // белый, почти непрозрачный
-	bgColor := color.NRGBA{0, 0, 0, 120}         // полупрозрачный черный фон
+	switch params.Fit {
+	case domain.FitContain:
+		return imaging.Fit(img, width, height, filter)
+	case domain.FitFill:
+		return imaging.Fill(img, width, height, anchor, filter)
+	default:
+		return imaging.Resize(img, width, height, filter)
+	}
+}
 
-	// Определяем размер текста
-	textWidth := len(watermarkText) * 10 // простая оценка ширины
-	textHeight := 20
+func crop(img image.Image, params domain.CropParams) (image.Image, error) {
+	bounds := img.Bounds()
+	rect := image.Rect(params.X, params.Y, params.X+params.Width, params.Y+params.Height).
+		Add(bounds.Min).
+		Intersect(bounds)
+	if rect.Empty() {
+		return nil, fmt.Errorf("crop area %dx%d+%d+%d is outside of %dx%d image",
+			params.Width, params.Height, params.X, params.Y, bounds.Dx(), bounds.Dy())
+	}
 
-	// Позиция в нижнем правом углу с отступом
-	margin := 10
-	x := width - textWidth - margin
-	y := height - textHeight - margin
+	return imaging.Crop(img, rect), nil
+}
 
-	// Рисуем фон под текст
-	rect := image.Rect(x, y, x+textWidth, y+textHeight)
-	draw.Draw(rgba, rect, &image.Uniform{bgColor}, image.Point{}, draw.Over)
+func encode(img image.Image, params domain.EncodeParams) ([]byte, error) {
+	format, ok := formats[params.Format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format %q", params.Format)
+	}
 
-	// Рисуем сам текст
-	point := fixed.Point26_6{
-		X: fixed.I(x + 2),
-		Y: fixed.I(y + textHeight - 4),
+	var options []imaging.EncodeOption
+	if params.Quality > 0 {
+		options = append(options, imaging.JPEGQuality(params.Quality))
+	}
+	if level, ok := pngCompression[params.Compression]; ok {
+		options = append(options, imaging.PNGCompressionLevel(level))
 	}
-	d := &font.Drawer{
-		Dst:  rgba,
-		Src:  image.NewUniform(textColor),
-		Face: basicfont.Face7x13,
-		Dot:  point,
+	if params.Colors > 0 {
+		options = append(options, imaging.GIFNumColors(params.Colors))
 	}
-	d.DrawString(watermarkText)
 
-	// Кодируем обратно в JPEG
 	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: 95}); err != nil {
-		return nil, errutils.Wrap("failed to encode watermarked image", err)
+	if err := imaging.Encode(&buf, img, format, options...); err != nil {
+		return nil, errutils.Wrap("failed to encode image", err)
 	}
 
-	return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"fmt"
+	"github.com/disintegration/imaging"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"image"
+	"io"
+)
+
+// Variants декодирует изображение один раз и кодирует его в каждой ширине набора в каждом
+// из форматов. Ширины больше оригинала пропускаются: увеличение не добавляет деталей, а srcset
+// и так выберет наибольшую версию. Если оригинал уже самой маленькой ширины, под ней
+// сохраняется одна версия в размере оригинала.
+func (p *Processor) Variants(img io.ReadCloser, params domain.VariantsParams) ([]domain.VariantImage, error) {
+	decodedImage, err := imaging.Decode(img, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, errutils.Wrap("failed to decode image", fmt.Errorf("%w: %w", domain.ErrUndecodableImage, err))
+	}
+
+	originalWidth := decodedImage.Bounds().Dx()
+	var widths []int
+	for _, w := range params.Widths {
+		if w <= originalWidth {
+			widths = append(widths, w)
+		}
+	}
+	if len(widths) == 0 {
+		widths = params.Widths[:1]
+	}
+
+	variants := make([]domain.VariantImage, 0, len(widths)*len(params.Outputs))
+	for _, w := range widths {
+		var resized image.Image = decodedImage
+		if w < originalWidth {
+			resized = imaging.Resize(decodedImage, w, 0, imaging.Lanczos)
+		}
+
+		for _, output := range params.Outputs {
+			data, err := encode(resized, output)
+			if err != nil {
+				return nil, err
+			}
+			variants = append(variants, domain.VariantImage{
+				TargetWidth: w,
+				Width:       resized.Bounds().Dx(),
+				Height:      resized.Bounds().Dy(),
+				Format:      output.Format,
+				Data:        data,
+			})
+		}
+	}
+
+	return variants, nil
+}
@@ -0,0 +1,253 @@
+package processor
+
+import (
+	"fmt"
+	"github.com/disintegration/imaging"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const (
+	defaultWatermarkText = "© MyBrand"
+	minWatermarkFontSize = 8  // px, мельче текст не читается
+	minWatermarkMarkSize = 16 // px, меньшая сторона ячейки знака при расчёте сетки tile
+	maxWatermarkTiles    = 4000
+)
+
+// assets загружает и кэширует шрифты и логотипы водяных знаков из каталогов на диске.
+type assets struct {
+	fontsDir string
+	logosDir string
+
+	mu    sync.Mutex
+	fonts map[string]*sfnt.Font
+	logos map[string]image.Image
+}
+
+func newAssets(fontsDir, logosDir string) *assets {
+	return &assets{
+		fontsDir: fontsDir,
+		logosDir: logosDir,
+		fonts:    make(map[string]*sfnt.Font),
+		logos:    make(map[string]image.Image),
+	}
+}
+
+// font возвращает шрифт по имени файла; пустое имя — встроенный Go Regular.
+func (a *assets) font(name string) (*sfnt.Font, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if f, ok := a.fonts[name]; ok {
+		return f, nil
+	}
+
+	data := goregular.TTF
+	if name != "" {
+		if a.fontsDir == "" {
+			return nil, fmt.Errorf("font %q: fonts directory is not configured", name)
+		}
+		var err error
+		if data, err = os.ReadFile(filepath.Join(a.fontsDir, name)); err != nil {
+			return nil, fmt.Errorf("font %q: %w", name, err)
+		}
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("font %q: %w", name, err)
+	}
+	a.fonts[name] = f
+
+	return f, nil
+}
+
+func (a *assets) logo(name string) (image.Image, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if logo, ok := a.logos[name]; ok {
+		return logo, nil
+	}
+	if a.logosDir == "" {
+		return nil, fmt.Errorf("logo %q: logos directory is not configured", name)
+	}
+
+	file, err := os.Open(filepath.Join(a.logosDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("logo %q: %w", name, err)
+	}
+	defer file.Close()
+
+	logo, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("logo %q: %w", name, err)
+	}
+	a.logos[name] = logo
+
+	return logo, nil
+}
+
+func (p *Processor) watermark(img image.Image, params domain.WatermarkParams) (image.Image, error) {
+	if params.Text == "" && params.Logo == "" {
+		params.Text, params.Logo = p.opts.WatermarkText, p.opts.WatermarkLogo
+		if params.Text == "" && params.Logo == "" {
+			params.Text = defaultWatermarkText
+		}
+	}
+
+	var (
+		mark image.Image
+		err  error
+	)
+	if params.Logo != "" {
+		mark, err = p.logoMark(img.Bounds(), params)
+	} else {
+		mark, err = p.textMark(img.Bounds(), params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Rotation != 0 {
+		mark = imaging.Rotate(mark, params.Rotation, color.Transparent)
+	}
+
+	dst := imaging.Clone(img)
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(params.Opacity * 255))})
+
+	points, err := placements(dst.Bounds(), mark.Bounds(), params)
+	if err != nil {
+		return nil, err
+	}
+	for _, pt := range points {
+		r := mark.Bounds().Sub(mark.Bounds().Min).Add(pt)
+		draw.DrawMask(dst, r, mark, mark.Bounds().Min, mask, image.Point{}, draw.Over)
+	}
+
+	return dst, nil
+}
+
+// textMark рисует текст на прозрачном фоне. Размер шрифта считается от меньшей стороны
+// изображения, поэтому знак одинаково заметен на превью и на оригинале.
+func (p *Processor) textMark(bounds image.Rectangle, params domain.WatermarkParams) (image.Image, error) {
+	f, err := p.assets.font(params.Font)
+	if err != nil {
+		return nil, err
+	}
+
+	size := params.FontSize * float64(min(bounds.Dx(), bounds.Dy()))
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    max(size, minWatermarkFontSize),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	textColor, err := parseColor(params.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ширина по реальным метрикам глифов, а не по количеству байт
+	metrics := face.Metrics()
+	width := font.MeasureString(face, params.Text).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+
+	mark := image.NewNRGBA(image.Rect(0, 0, width, height))
+	d := &font.Drawer{
+		Dst:  mark,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.Point26_6{X: 0, Y: metrics.Ascent},
+	}
+	d.DrawString(params.Text)
+
+	return mark, nil
+}
+
+func (p *Processor) logoMark(bounds image.Rectangle, params domain.WatermarkParams) (image.Image, error) {
+	logo, err := p.assets.logo(params.Logo)
+	if err != nil {
+		return nil, err
+	}
+
+	width := max(int(math.Round(params.LogoScale*float64(bounds.Dx()))), minWatermarkMarkSize)
+	return imaging.Resize(logo, width, 0, imaging.Lanczos), nil
+}
+
+// placements возвращает позиции левого верхнего угла знака: одну по anchor и смещениям
+// или сетку со сдвигом каждого второго ряда для режима tile.
+// Сетка, в которой знаков больше maxWatermarkTiles, отклоняется.
+func placements(bounds, mark image.Rectangle, params domain.WatermarkParams) ([]image.Point, error) {
+	w, h := mark.Dx(), mark.Dy()
+
+	if params.Mode == domain.WatermarkTile {
+		// Промежуток между знаками — половина знака; ячейка не меньше minWatermarkMarkSize
+		cellW, cellH := max(w, minWatermarkMarkSize), max(h, minWatermarkMarkSize)
+		stepX, stepY := cellW+cellW/2, cellH+cellH/2
+
+		cols, rows := (bounds.Dx()+w)/stepX+2, (bounds.Dy()+h)/stepY+1
+		if cols*rows > maxWatermarkTiles {
+			return nil, fmt.Errorf("%w: watermark is too small to tile %dx%d image, increase font size or logo scale",
+				domain.ErrInvalidTaskParams, bounds.Dx(), bounds.Dy())
+		}
+
+		points := make([]image.Point, 0, cols*rows)
+		for row, y := 0, bounds.Min.Y-h+params.OffsetY; y < bounds.Max.Y; row, y = row+1, y+stepY {
+			shift := (row % 2) * stepX / 2
+			for x := bounds.Min.X - w + params.OffsetX + shift; x < bounds.Max.X; x += stepX {
+				points = append(points, image.Pt(x, y))
+			}
+		}
+		return points, nil
+	}
+
+	var x, y int
+	switch params.Anchor {
+	case "top_left", "left", "bottom_left":
+		x = bounds.Min.X + params.OffsetX
+	case "top_right", "right", "bottom_right":
+		x = bounds.Max.X - w - params.OffsetX
+	default:
+		x = bounds.Min.X + (bounds.Dx()-w)/2 + params.OffsetX
+	}
+	switch params.Anchor {
+	case "top_left", "top", "top_right":
+		y = bounds.Min.Y + params.OffsetY
+	case "bottom_left", "bottom", "bottom_right":
+		y = bounds.Max.Y - h - params.OffsetY
+	default:
+		y = bounds.Min.Y + (bounds.Dy()-h)/2 + params.OffsetY
+	}
+
+	return []image.Point{image.Pt(x, y)}, nil
+}
+
+// parseColor разбирает цвет в формате #rrggbb.
+func parseColor(s string) (color.NRGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
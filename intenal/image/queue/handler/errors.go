@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+)
+
+// taskError — ошибка обработки задачи с указанием её класса.
+type taskError struct {
+	class domain.ErrorClass
+	err   error
+}
+
+func (e *taskError) Error() string {
+	return e.err.Error()
+}
+
+func (e *taskError) Unwrap() error {
+	return e.err
+}
+
+func withClass(class domain.ErrorClass, err error) error {
+	return &taskError{class: class, err: err}
+}
+
+func classOf(err error) domain.ErrorClass {
+	var te *taskError
+	if errors.As(err, &te) {
+		return te.class
+	}
+	return domain.ErrClassInternal
+}
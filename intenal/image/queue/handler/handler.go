@@ -0,0 +1,422 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ilam072/image-processor/intenal/queue"
+	"github.com/ilam072/image-processor/intenal/storage"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/intenal/types/dto"
+	"github.com/ilam072/image-processor/intenal/utils"
+	"github.com/wb-go/wbf/zlog"
+	"io"
+	"runtime/debug"
+	"time"
+)
+
+type Consumer interface {
+	Consume(ctx context.Context) (queue.Message, error)
+	Commit(ctx context.Context, msg queue.Message) error
+	Close() error
+}
+
+// MessageExtender реализуют очереди, которые скрывают выданное сообщение на ограниченное время
+// (postgres): пока задача в работе, сообщение не должно достаться другому воркеру.
+type MessageExtender interface {
+	Extend(ctx context.Context, msg queue.Message, ttl time.Duration) error
+}
+
+type Image interface {
+	GetPathByID(ctx context.Context, ID uuid.UUID) (string, error)
+}
+
+type ImageProcessor interface {
+	Pipeline(img io.ReadCloser, ops []domain.Operation) (io.ReadCloser, error)
+	Variants(img io.ReadCloser, params domain.VariantsParams) ([]domain.VariantImage, error)
+}
+
+type Task interface {
+	GetTaskByID(ctx context.Context, id int) (dto.Task, error)
+	UpdateTaskStatus(ctx context.Context, ID int, status string) error
+	RenewLease(ctx context.Context, ID int, ttl time.Duration) error
+	StartAttempt(ctx context.Context, taskID int, workerID string) (int, error)
+	FinishAttempt(ctx context.Context, attemptID int, errClass string, errMessage string) error
+}
+
+type DeadLetterProducer interface {
+	ProduceDeadLetter(ctx context.Context, msg dto.DeadLetterMessage) error
+}
+
+const defaultLeaseTTL = time.Minute
+
+// Opts — параметры обработки задач воркером.
+type Opts struct {
+	WorkerID    string        // идентификатор воркера в истории попыток
+	Retry       RetryPolicy   // повторы при временных ошибках
+	Workers     int           // количество воркеров
+	MaxInFlight int           // максимум сообщений в обработке одновременно
+	LeaseTTL    time.Duration // время аренды задачи, продлевается пока задача в работе
+}
+
+type Handler struct {
+	task      Task
+	image     Image
+	storage   storage.Storage
+	processor ImageProcessor
+	c         Consumer
+	dlq       DeadLetterProducer
+	opts      Opts
+}
+
+func New(
+	task Task,
+	image Image,
+	storage storage.Storage,
+	processor ImageProcessor,
+	c Consumer,
+	dlq DeadLetterProducer,
+	opts Opts,
+) *Handler {
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = defaultLeaseTTL
+	}
+	return &Handler{
+		task:      task,
+		image:     image,
+		storage:   storage,
+		processor: processor,
+		c:         c,
+		dlq:       dlq,
+		opts:      opts,
+	}
+}
+
+// handleMessage обрабатывает задачу, повторяя попытки при временных ошибках.
+// Возвращает true, если обработка завершена (успехом или окончательной ошибкой с отправкой в DLQ)
+// и offset можно коммитить; false — если ожидание повтора прервано остановкой сервиса.
+func (h *Handler) handleMessage(ctx context.Context, shutdown <-chan struct{}, msg queue.Message) bool {
+	var taskMsg dto.TaskMessage
+	if err := json.Unmarshal(msg.Value, &taskMsg); err != nil {
+		zlog.Logger.Warn().Err(err).Bytes("key", msg.Key).Msg("invalid task message format")
+		h.deadLetter(ctx, msg, 0, 0, withClass(domain.ErrClassInvalidTask, err))
+		return true
+	}
+
+	stopLease := h.keepLease(ctx, taskMsg.ID, msg)
+	defer stopLease()
+
+	for attempt := 1; ; attempt++ {
+		err := h.attempt(ctx, taskMsg.ID)
+		if err == nil {
+			break
+		}
+
+		class := classOf(err)
+		if class == domain.ErrClassCanceled {
+			zlog.Logger.Info().Err(err).Int("task_id", taskMsg.ID).Msg("task canceled, result discarded")
+			break
+		}
+		if !isTransient(class) || attempt > h.opts.Retry.MaxRetries {
+			zlog.Logger.Error().
+				Err(err).
+				Int("task_id", taskMsg.ID).
+				Int("attempt", attempt).
+				Str("error_class", string(class)).
+				Msg("task failed")
+			_ = h.task.UpdateTaskStatus(ctx, taskMsg.ID, "failed")
+			h.deadLetter(ctx, msg, taskMsg.ID, attempt, err)
+			break
+		}
+
+		delay := h.opts.Retry.backoff(attempt)
+		zlog.Logger.Warn().
+			Err(err).
+			Int("task_id", taskMsg.ID).
+			Int("attempt", attempt).
+			Str("error_class", string(class)).
+			Dur("delay", delay).
+			Msg("task attempt failed, retrying")
+		_ = h.task.UpdateTaskStatus(ctx, taskMsg.ID, "retrying")
+
+		select {
+		case <-shutdown:
+			// Без коммита: сообщение будет прочитано заново после перезапуска
+			return false
+		case <-time.After(delay):
+		}
+	}
+
+	return true
+}
+
+// attempt выполняет одну попытку обработки задачи и записывает её в историю.
+func (h *Handler) attempt(ctx context.Context, taskID int) error {
+	task, err := h.task.GetTaskByID(ctx, taskID)
+	if err != nil {
+		// Строки задач удаляются только вместе с изображением
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			return withClass(domain.ErrClassCanceled, err)
+		}
+		return withClass(domain.ErrClassDatabase, err)
+	}
+
+	// Доставка at-least-once: повторное сообщение для завершённой задачи пропускаем
+	if task.Status == string(domain.Completed) {
+		zlog.Logger.Info().Int("task_id", task.ID).Msg("task already completed, skipping duplicate message")
+		return nil
+	}
+	if task.Status == string(domain.Canceled) {
+		zlog.Logger.Info().Int("task_id", task.ID).Msg("task canceled, skipping")
+		return nil
+	}
+
+	attemptID, err := h.task.StartAttempt(ctx, task.ID, h.opts.WorkerID)
+	if err != nil {
+		return withClass(domain.ErrClassDatabase, err)
+	}
+
+	err = h.runTask(ctx, task)
+	h.finishAttempt(ctx, attemptID, err)
+
+	return err
+}
+
+func (h *Handler) runTask(ctx context.Context, task dto.Task) (err error) {
+	// Паника в обработке (например, в библиотеке изображений) завершает ошибкой только эту задачу
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Logger.Error().Str("panic", fmt.Sprint(r)).Bytes("stack", debug.Stack()).Int("task_id", task.ID).Msg("panic while processing task")
+			err = withClass(domain.ErrClassProcessing, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if err := h.task.UpdateTaskStatus(ctx, task.ID, "processing"); err != nil {
+		return withClass(domain.ErrClassDatabase, err)
+	}
+
+	if err := h.processTask(ctx, task); err != nil {
+		return err
+	}
+
+	if err := h.task.UpdateTaskStatus(ctx, task.ID, "completed"); err != nil {
+		return withClass(domain.ErrClassDatabase, err)
+	}
+
+	return nil
+}
+
+// keepLease продлевает аренду задачи, пока она в работе, чтобы reaper не счёл её зависшей,
+// и скрытие сообщения в очереди, если очередь это поддерживает.
+func (h *Handler) keepLease(ctx context.Context, taskID int, msg queue.Message) (stop func()) {
+	extender, _ := h.c.(MessageExtender)
+	renew := func() {
+		if err := h.task.RenewLease(ctx, taskID, h.opts.LeaseTTL); err != nil {
+			zlog.Logger.Warn().Err(err).Int("task_id", taskID).Msg("failed to renew task lease")
+		}
+		if extender == nil {
+			return
+		}
+		if err := extender.Extend(ctx, msg, h.opts.LeaseTTL); err != nil {
+			zlog.Logger.Warn().Err(err).Int("task_id", taskID).Msg("failed to extend queue message visibility")
+		}
+	}
+	renew()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(h.opts.LeaseTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				renew()
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func (h *Handler) deadLetter(ctx context.Context, msg queue.Message, taskID int, attempts int, err error) {
+	dl := dto.DeadLetterMessage{
+		TaskID:     taskID,
+		Payload:    msg.Value,
+		ErrorClass: string(classOf(err)),
+		Error:      err.Error(),
+		Attempts:   attempts,
+		FailedAt:   time.Now(),
+	}
+
+	if err := h.dlq.ProduceDeadLetter(ctx, dl); err != nil {
+		zlog.Logger.Error().Err(err).Int("task_id", taskID).Msg("failed to send message to dead letter topic")
+	}
+}
+
+// processTask загружает оригинал, обрабатывает его и сохраняет результат по processed path.
+// Для variants по processed path сохраняется манифест, а версии — рядом с ним.
+func (h *Handler) processTask(ctx context.Context, task dto.Task) error {
+	originalPath, err := h.image.GetPathByID(ctx, task.ImageID)
+	if err != nil {
+		// Задача существует, а изображения нет — значит, его удаляют
+		if errors.Is(err, domain.ErrImageNotFound) {
+			return withClass(domain.ErrClassCanceled, err)
+		}
+		return withClass(domain.ErrClassDatabase, err)
+	}
+
+	img, err := h.storage.Load(ctx, originalPath)
+	if err != nil {
+		return withClass(domain.ErrClassStorage, err)
+	}
+	defer img.Close()
+
+	var saved []string
+	if domain.TaskType(task.Type) == domain.Variants {
+		saved, err = h.saveVariants(ctx, img, task)
+	} else {
+		saved, err = h.saveProcessed(ctx, img, task)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Изображение могли удалить во время обработки. Проверка идёт после сохранения:
+	// если удаление началось раньше, результат удаляем сами, иначе его найдёт и удалит DeleteImage
+	if err := h.checkCanceled(ctx, task); err != nil {
+		if classOf(err) == domain.ErrClassCanceled {
+			for _, path := range saved {
+				if err := h.storage.Delete(ctx, path); err != nil {
+					zlog.Logger.Error().Err(err).Int("task_id", task.ID).Str("path", path).Msg("failed to delete result of canceled task")
+				}
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (h *Handler) saveProcessed(ctx context.Context, img io.ReadCloser, task dto.Task) ([]string, error) {
+	processedImg, err := h.process(img, task)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := task.Params.OutputFormat().ContentType()
+	if err := h.storage.Save(ctx, task.ProcessedPath, processedImg, contentType); err != nil {
+		return nil, withClass(domain.ErrClassStorage, err)
+	}
+
+	return []string{task.ProcessedPath}, nil
+}
+
+// saveVariants сохраняет версии из набора и последним — манифест: если манифест есть, все версии на месте.
+// Возвращает пути сохранённых объектов.
+func (h *Handler) saveVariants(ctx context.Context, img io.ReadCloser, task dto.Task) ([]string, error) {
+	if task.Params.Variants == nil {
+		return nil, withClass(domain.ErrClassInvalidTask, fmt.Errorf("%w: variants params are missing", domain.ErrInvalidTaskParams))
+	}
+
+	variants, err := h.processor.Variants(img, *task.Params.Variants)
+	if err != nil {
+		if errors.Is(err, domain.ErrUndecodableImage) {
+			return nil, withClass(domain.ErrClassDecode, err)
+		}
+		return nil, withClass(domain.ErrClassProcessing, err)
+	}
+
+	manifest := domain.VariantManifest{
+		Preset:   task.Params.Variants.Preset,
+		Variants: make([]domain.Variant, 0, len(variants)),
+	}
+	saved := make([]string, 0, len(variants)+1)
+	for _, v := range variants {
+		path := utils.VariantPath(task.ProcessedPath, v.TargetWidth, v.Format.Ext())
+		if err := h.storage.Save(ctx, path, bytes.NewReader(v.Data), v.Format.ContentType()); err != nil {
+			return nil, withClass(domain.ErrClassStorage, err)
+		}
+		saved = append(saved, path)
+
+		manifest.Variants = append(manifest.Variants, domain.Variant{
+			Path:        path,
+			TargetWidth: v.TargetWidth,
+			Width:       v.Width,
+			Height:      v.Height,
+			Format:      v.Format,
+			Size:        int64(len(v.Data)),
+		})
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, withClass(domain.ErrClassProcessing, err)
+	}
+	if err := h.storage.Save(ctx, task.ProcessedPath, bytes.NewReader(data), "application/json"); err != nil {
+		return nil, withClass(domain.ErrClassStorage, err)
+	}
+
+	return append(saved, task.ProcessedPath), nil
+}
+
+// checkCanceled возвращает ошибку класса canceled, если задача отменена или изображение удалено.
+func (h *Handler) checkCanceled(ctx context.Context, task dto.Task) error {
+	current, err := h.task.GetTaskByID(ctx, task.ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			return withClass(domain.ErrClassCanceled, err)
+		}
+		return withClass(domain.ErrClassDatabase, err)
+	}
+	if current.Status == string(domain.Canceled) {
+		return withClass(domain.ErrClassCanceled, errors.New("task canceled"))
+	}
+
+	if _, err := h.image.GetPathByID(ctx, task.ImageID); err != nil {
+		if errors.Is(err, domain.ErrImageNotFound) {
+			return withClass(domain.ErrClassCanceled, err)
+		}
+		return withClass(domain.ErrClassDatabase, err)
+	}
+
+	return nil
+}
+
+func (h *Handler) finishAttempt(ctx context.Context, attemptID int, err error) {
+	var errClass, errMessage string
+	if err != nil {
+		errClass, errMessage = string(classOf(err)), err.Error()
+	}
+
+	if err := h.task.FinishAttempt(ctx, attemptID, errClass, errMessage); err != nil {
+		zlog.Logger.Error().Err(err).Int("attempt_id", attemptID).Msg("failed to finish task attempt")
+	}
+}
+
+func (h *Handler) process(img io.ReadCloser, task dto.Task) (io.ReadCloser, error) {
+	// Любая задача выполняется как конвейер операций с кодированием в выходной формат
+	ops, err := domain.TaskOperations(domain.TaskType(task.Type), task.Params)
+	if err != nil {
+		return nil, withClass(domain.ErrClassInvalidTask, err)
+	}
+
+	processed, err := h.processor.Pipeline(img, ops)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUndecodableImage):
+			return nil, withClass(domain.ErrClassDecode, err)
+		case errors.Is(err, domain.ErrInvalidTaskParams):
+			// Параметры, которые можно проверить только по размеру изображения
+			return nil, withClass(domain.ErrClassInvalidTask, err)
+		}
+		return nil, withClass(domain.ErrClassProcessing, err)
+	}
+
+	return processed, nil
+}
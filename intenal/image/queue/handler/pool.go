@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/queue"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/wb-go/wbf/zlog"
+	"hash/fnv"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Задержка перед повторным чтением после ошибки брокера
+const (
+	consumeBackoffMin = 100 * time.Millisecond
+	consumeBackoffMax = 5 * time.Second
+)
+
+// Start читает сообщения и распределяет их по воркерам. Сообщения с одинаковым ключом
+// (ID задачи) всегда попадают к одному воркеру и обрабатываются по порядку.
+// Порядок подтверждений для брокера (например, offset'ы Kafka) обеспечивает адаптер очереди.
+// После отмены ctx новые сообщения не читаются, а Start дожидается завершения уже взятых в работу.
+// Обработка и коммит идут в workCtx: его отменяют, только если задачи не успели завершиться при остановке.
+func (h *Handler) Start(ctx, workCtx context.Context) error {
+	workers := max(h.opts.Workers, 1)
+	maxInFlight := max(h.opts.MaxInFlight, workers)
+
+	slots := make(chan struct{}, maxInFlight)
+	queues := make([]chan queue.Message, workers)
+
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan queue.Message, maxInFlight)
+
+		wg.Add(1)
+		go func(jobs <-chan queue.Message) {
+			defer wg.Done()
+			for msg := range jobs {
+				if h.safeHandleMessage(workCtx, ctx.Done(), msg) {
+					h.commit(workCtx, msg)
+				}
+				<-slots
+			}
+		}(queues[i])
+	}
+
+	defer func() {
+		for _, jobs := range queues {
+			close(jobs)
+		}
+		wg.Wait()
+	}()
+
+	backoff := consumeBackoffMin
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case slots <- struct{}{}:
+		}
+
+		msg, err := h.c.Consume(ctx)
+		if err != nil {
+			<-slots
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, queue.ErrClosed) {
+				return err
+			}
+
+			zlog.Logger.Warn().Err(err).Dur("retry_in", backoff).Msg("failed to consume message")
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, consumeBackoffMax)
+			continue
+		}
+		backoff = consumeBackoffMin
+
+		queues[workerIndex(msg, workers)] <- msg
+	}
+}
+
+// safeHandleMessage не даёт панике при обработке сообщения завершить процесс:
+// сообщение уходит в DLQ и подтверждается, как после окончательной ошибки.
+func (h *Handler) safeHandleMessage(ctx context.Context, shutdown <-chan struct{}, msg queue.Message) (done bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Logger.Error().
+				Str("panic", fmt.Sprint(r)).
+				Bytes("stack", debug.Stack()).
+				Bytes("key", msg.Key).
+				Msg("panic while handling message")
+			h.deadLetter(ctx, msg, 0, 0, withClass(domain.ErrClassInternal, fmt.Errorf("panic: %v", r)))
+			done = true
+		}
+	}()
+
+	return h.handleMessage(ctx, shutdown, msg)
+}
+
+func (h *Handler) commit(ctx context.Context, msg queue.Message) {
+	if err := h.c.Commit(ctx, msg); err != nil {
+		zlog.Logger.Error().Err(err).Bytes("key", msg.Key).Msg("failed to commit message")
+	}
+}
+
+func workerIndex(msg queue.Message, workers int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write(msg.Key)
+
+	return int(hash.Sum32() % uint32(workers))
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryPolicy задаёт количество повторов и экспоненциальную задержку между ними.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// backoff возвращает задержку перед повтором номер attempt (начиная с 1):
+// base * 2^(attempt-1), ограниченную MaxDelay, со случайным разбросом в [d/2, d].
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	base, maxDelay := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// isTransient сообщает, имеет ли смысл повторять задачу после ошибки данного класса.
+func isTransient(class domain.ErrorClass) bool {
+	switch class {
+	case domain.ErrClassStorage, domain.ErrClassDatabase, domain.ErrClassInternal:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration // задержка до разброса
+	}{
+		{name: "first attempt", policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, attempt: 1, want: time.Second},
+		{name: "second attempt", policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, attempt: 2, want: 2 * time.Second},
+		{name: "fifth attempt", policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, attempt: 5, want: 16 * time.Second},
+		{name: "capped", policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, attempt: 6, want: 30 * time.Second},
+		{name: "large attempt does not overflow", policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, attempt: 1000, want: 30 * time.Second},
+		{name: "zero attempt", policy: RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, attempt: 0, want: time.Second},
+		{name: "custom base", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, attempt: 4, want: 800 * time.Millisecond},
+		{name: "custom cap", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, attempt: 5, want: time.Second},
+		{name: "base above cap", policy: RetryPolicy{BaseDelay: time.Minute, MaxDelay: time.Second}, attempt: 1, want: time.Second},
+		{name: "defaults", policy: RetryPolicy{}, attempt: 1, want: defaultRetryBaseDelay},
+		{name: "default cap", policy: RetryPolicy{}, attempt: 10, want: defaultRetryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Разброс случайный, поэтому проверяются границы [d/2, d] на многих попытках
+			for range 1000 {
+				got := tt.policy.backoff(tt.attempt)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("backoff(%d) = %v, want in [%v, %v]", tt.attempt, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+	seen := make(map[time.Duration]struct{})
+	for range 100 {
+		seen[policy.backoff(3)] = struct{}{}
+	}
+	if len(seen) < 2 {
+		t.Fatal("backoff(3) always returned the same delay, want jitter")
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		class domain.ErrorClass
+		want  bool
+	}{
+		{class: domain.ErrClassStorage, want: true},
+		{class: domain.ErrClassDatabase, want: true},
+		{class: domain.ErrClassInternal, want: true},
+		{class: domain.ErrClassInvalidTask, want: false},
+		{class: domain.ErrClassNotFound, want: false},
+		{class: domain.ErrClassDecode, want: false},
+		{class: domain.ErrClassProcessing, want: false},
+		{class: domain.ErrClassLeaseExpired, want: false},
+		{class: domain.ErrClassCanceled, want: false},
+		{class: domain.ErrorClass("unknown"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.class), func(t *testing.T) {
+			if got := isTransient(tt.class); got != tt.want {
+				t.Fatalf("isTransient(%q) = %v, want %v", tt.class, got, tt.want)
+			}
+		})
+	}
+}
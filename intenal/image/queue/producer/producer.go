@@ -0,0 +1,50 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ilam072/image-processor/intenal/queue"
+	"github.com/ilam072/image-processor/intenal/types/dto"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"strconv"
+)
+
+type Producer struct {
+	pub queue.Publisher
+}
+
+func New(pub queue.Publisher) *Producer {
+	return &Producer{pub: pub}
+}
+
+func (p *Producer) Produce(ctx context.Context, taskID int) error {
+	task := dto.TaskMessage{ID: taskID}
+
+	bytes, err := json.Marshal(task)
+	if err != nil {
+		return errutils.Wrap("failed to marshal task id", err)
+	}
+	key := []byte(strconv.Itoa(taskID))
+	if err := p.pub.Publish(ctx, key, bytes); err != nil {
+		return errutils.Wrap("failed to publish task id", err)
+	}
+
+	return nil
+}
+
+func (p *Producer) ProduceDeadLetter(ctx context.Context, msg dto.DeadLetterMessage) error {
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		return errutils.Wrap("failed to marshal dead letter message", err)
+	}
+	key := []byte(strconv.Itoa(msg.TaskID))
+	if err := p.pub.Publish(ctx, key, bytes); err != nil {
+		return errutils.Wrap("failed to publish dead letter message", err)
+	}
+
+	return nil
+}
+
+func (p *Producer) Close() error {
+	return p.pub.Close()
+}
@@ -22,9 +22,30 @@ func New(db *dbpg.DB) *ImageRepo {
 func (r *ImageRepo) CreateImage(ctx context.Context, image domain.Image) error {
 	const op = "repo.image.Create"
 
-	query := `INSERT INTO images(id, original_path) VALUES ($1, $2)`
+	query := `
+		INSERT INTO images(
+			id, original_path, original_filename, mime_type, size_bytes, checksum_sha256,
+			width, height, color_model, camera_make, camera_model, captured_at, orientation
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
 
-	if _, err := r.db.ExecContext(ctx, query, image.ID, image.Path); err != nil {
+	md := image.Metadata
+	if _, err := r.db.ExecContext(ctx, query,
+		image.ID,
+		image.Path,
+		md.OriginalFilename,
+		md.MimeType,
+		md.Size,
+		md.Checksum,
+		md.Width,
+		md.Height,
+		md.ColorModel,
+		md.CameraMake,
+		md.CameraModel,
+		md.CapturedAt,
+		md.Orientation,
+	); err != nil {
 		return errutils.Wrap(op, err)
 	}
 
@@ -35,16 +56,32 @@ func (r *ImageRepo) GetImageByID(ctx context.Context, ID uuid.UUID) (domain.Imag
 	const op = "repo.image.Get"
 
 	query := `
-		SELECT id, original_path, uploaded_at
+		SELECT id, original_path, uploaded_at,
+		       COALESCE(original_filename, ''), COALESCE(mime_type, ''), COALESCE(size_bytes, 0),
+		       COALESCE(checksum_sha256, ''), COALESCE(width, 0), COALESCE(height, 0),
+		       COALESCE(color_model, ''), COALESCE(camera_make, ''), COALESCE(camera_model, ''),
+		       captured_at, COALESCE(orientation, 0)
 		FROM images
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var image domain.Image
+	md := &image.Metadata
 	if err := r.db.QueryRowContext(ctx, query, ID).Scan(
 		&image.ID,
 		&image.Path,
 		&image.UploadedAt,
+		&md.OriginalFilename,
+		&md.MimeType,
+		&md.Size,
+		&md.Checksum,
+		&md.Width,
+		&md.Height,
+		&md.ColorModel,
+		&md.CameraMake,
+		&md.CameraModel,
+		&md.CapturedAt,
+		&md.Orientation,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Image{}, errutils.Wrap(op, repo.ErrImageNotFound)
@@ -58,7 +95,7 @@ func (r *ImageRepo) GetImageByID(ctx context.Context, ID uuid.UUID) (domain.Imag
 func (r *ImageRepo) GetPathByID(ctx context.Context, ID uuid.UUID) (string, error) {
 	const op = "repo.image.GetPathByID"
 
-	query := `SELECT original_path FROM images WHERE id = $1`
+	query := `SELECT original_path FROM images WHERE id = $1 AND deleted_at IS NULL`
 
 	var path string
 
@@ -72,6 +109,83 @@ func (r *ImageRepo) GetPathByID(ctx context.Context, ID uuid.UUID) (string, erro
 	return path, nil
 }
 
+// ListImagesPage возвращает до limit изображений с id больше after, включая помеченные удалёнными.
+// Метаданные не заполняются.
+func (r *ImageRepo) ListImagesPage(ctx context.Context, after uuid.UUID, limit int) ([]domain.Image, error) {
+	const op = "repo.image.ListPage"
+
+	query := `
+		SELECT id, original_path, uploaded_at, deleted_at
+		FROM images
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	var images []domain.Image
+	for rows.Next() {
+		var image domain.Image
+		if err := rows.Scan(&image.ID, &image.Path, &image.UploadedAt, &image.DeletedAt); err != nil {
+			return nil, errutils.Wrap(op, err)
+		}
+		images = append(images, image)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return images, nil
+}
+
+// MarkImageDeleted помечает изображение удалённым и отменяет его незавершённые задачи.
+// Повторный вызов для уже помеченного изображения возвращает тот же путь, чтобы
+// прерванное удаление можно было довести до конца.
+func (r *ImageRepo) MarkImageDeleted(ctx context.Context, ID uuid.UUID) (string, error) {
+	const op = "repo.image.MarkDeleted"
+
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		UPDATE images
+		SET deleted_at = COALESCE(deleted_at, NOW())
+		WHERE id = $1
+		RETURNING original_path
+	`
+
+	var path string
+	if err := tx.QueryRowContext(ctx, query, ID).Scan(&path); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errutils.Wrap(op, repo.ErrImageNotFound)
+		}
+		return "", errutils.Wrap(op, err)
+	}
+
+	cancel := `
+		UPDATE tasks
+		SET status = 'canceled', finished_at = NOW(), lease_until = NULL
+		WHERE image_id = $1 AND status IN ('queued', 'processing', 'retrying')
+	`
+	if _, err := tx.ExecContext(ctx, cancel, ID); err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+
+	return path, nil
+}
+
 func (r *ImageRepo) DeleteImageByID(ctx context.Context, ID uuid.UUID) error {
 	const op = "repo.image.DeleteByID"
 
@@ -2,46 +2,120 @@ package rest
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/ilam072/image-processor/intenal/response"
+	"github.com/ilam072/image-processor/intenal/storage"
 	"github.com/ilam072/image-processor/intenal/types/domain"
-	"github.com/ilam072/image-processor/intenal/utils"
+	"github.com/ilam072/image-processor/intenal/types/dto"
 	"github.com/wb-go/wbf/ginext"
 	"github.com/wb-go/wbf/zlog"
 	"io"
 	"mime"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Image interface {
-	UploadImage(ctx context.Context, file io.Reader, ext string) (string, error)
+	UploadImage(ctx context.Context, file io.Reader, filename string) (string, error)
 	GetPathByID(ctx context.Context, ID uuid.UUID) (string, error)
+	GetImageMetadata(ctx context.Context, ID uuid.UUID) (dto.ImageMetadata, error)
 	DeleteImage(ctx context.Context, ID uuid.UUID) error
 }
 
-type Storage interface {
-	Load(ctx context.Context, name string) (io.ReadCloser, error)
+type Task interface {
+	GetTaskByID(ctx context.Context, id int) (dto.Task, error)
+	GetLatestTask(ctx context.Context, imageID uuid.UUID, processedPath string) (dto.Task, error)
+	ProcessedPath(ctx context.Context, originalPath string, action string, params dto.TaskParams) (string, error)
 }
 
+type Signer interface {
+	Sign(path string, query url.Values, ttl time.Duration) (url.Values, time.Time)
+}
+
+type Transformer interface {
+	Transform(ctx context.Context, ID uuid.UUID, params dto.TransformParams) (string, error)
+	Validate(originalPath string, params dto.TransformParams) error
+}
+
+// Opts — параметры HTTP-обработчика изображений.
+type Opts struct {
+	MaxUploadSize int64         // максимальный размер загружаемого файла в байтах
+	SignTTL       time.Duration // срок действия подписанной ссылки по умолчанию
+	SignMaxTTL    time.Duration // максимальный срок, который можно запросить
+
+	CacheControlOriginal   string // Cache-Control для оригиналов
+	CacheControlDerivative string // Cache-Control для обработанных изображений
+}
+
+const (
+	defaultSignTTL    = time.Hour
+	defaultSignMaxTTL = 7 * 24 * time.Hour
+
+	// Оригинал может быть заменён или удалён, поэтому кэшируется ненадолго.
+	// Путь производного однозначно определяется параметрами, содержимое по нему не меняется.
+	defaultCacheControlOriginal   = "public, max-age=3600"
+	defaultCacheControlDerivative = "public, max-age=31536000, immutable"
+)
+
+// Запас на заголовки и границы multipart сверх размера самого файла
+const multipartOverhead = 1 << 20
+
 type Handler struct {
-	image   Image
-	storage Storage
+	image       Image
+	storage     storage.Storage
+	task        Task
+	transformer Transformer
+	signer      Signer
+	opts        Opts
 }
 
-func NewImageHandler(image Image, storage Storage) *Handler {
-	return &Handler{image: image, storage: storage}
+// NewImageHandler создаёт обработчик. signer может быть nil — тогда ссылки выдаются без подписи.
+func NewImageHandler(image Image, storage storage.Storage, task Task, transformer Transformer, signer Signer, opts Opts) *Handler {
+	if opts.SignTTL <= 0 {
+		opts.SignTTL = defaultSignTTL
+	}
+	if opts.SignMaxTTL <= 0 {
+		opts.SignMaxTTL = defaultSignMaxTTL
+	}
+	if opts.CacheControlOriginal == "" {
+		opts.CacheControlOriginal = defaultCacheControlOriginal
+	}
+	if opts.CacheControlDerivative == "" {
+		opts.CacheControlDerivative = defaultCacheControlDerivative
+	}
+
+	return &Handler{image: image, storage: storage, task: task, transformer: transformer, signer: signer, opts: opts}
 }
 
 func (h *Handler) UploadImage(c *ginext.Context) {
+	if h.opts.MaxUploadSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.opts.MaxUploadSize+multipartOverhead)
+	}
+
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.Error("file is too large").WriteJSON(c, http.StatusRequestEntityTooLarge)
+			return
+		}
 		response.Error("failed to get file").WriteJSON(c, http.StatusBadRequest)
 		return
 	}
 
+	if h.opts.MaxUploadSize > 0 && fileHeader.Size > h.opts.MaxUploadSize {
+		response.Error("file is too large").WriteJSON(c, http.StatusRequestEntityTooLarge)
+		return
+	}
+
 	file, err := fileHeader.Open()
 	if err != nil {
 		zlog.Logger.Error().
@@ -55,9 +129,26 @@ func (h *Handler) UploadImage(c *ginext.Context) {
 	}
 	defer file.Close()
 
-	ext := filepath.Ext(fileHeader.Filename)
-	ID, err := h.image.UploadImage(c.Request.Context(), file, ext)
+	ID, err := h.image.UploadImage(c.Request.Context(), file, filepath.Base(fileHeader.Filename))
 	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrEmptyFile):
+			response.Error("file is empty").WriteJSON(c, http.StatusBadRequest)
+			return
+		case errors.Is(err, domain.ErrFileTooLarge):
+			response.Error("file is too large").WriteJSON(c, http.StatusRequestEntityTooLarge)
+			return
+		case errors.Is(err, domain.ErrUnsupportedFormat):
+			response.Error("unsupported image format").WriteJSON(c, http.StatusUnsupportedMediaType)
+			return
+		case errors.Is(err, domain.ErrUndecodableImage):
+			response.Error("image is corrupted or cannot be decoded").WriteJSON(c, http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, domain.ErrImageTooLarge):
+			response.Error("image dimensions exceed limits").WriteJSON(c, http.StatusUnprocessableEntity)
+			return
+		}
+
 		zlog.Logger.Error().
 			Err(err).
 			Int64("file_size", fileHeader.Size).
@@ -78,8 +169,158 @@ func (h *Handler) GetImage(c *ginext.Context) {
 	}
 
 	action := c.Query("processed")
-	if action != "" && action != "resize" && action != "thumbnail" && action != "watermark" {
-		response.Error("unexpected action").WriteJSON(c, http.StatusBadRequest)
+
+	originalPath, err := h.image.GetPathByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrImageNotFound) {
+			response.Error("image not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("image_id", id.String()).Msg("failed to get image path")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	path := originalPath
+	var params dto.TaskParams
+	if action != "" {
+		if err := c.ShouldBindQuery(&params); err != nil {
+			response.Error("invalid task params").WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+
+		// Путь считается так же, как при постановке задачи с теми же параметрами;
+		// action — встроенный тип задачи или имя пресета
+		path, err = h.task.ProcessedPath(c.Request.Context(), path, action, params)
+		if err != nil {
+			switch {
+			case errors.Is(err, domain.ErrInvalidTaskParams):
+				response.Error(err.Error()).WriteJSON(c, http.StatusBadRequest)
+			case errors.Is(err, domain.ErrPresetNotFound):
+				response.Error("unexpected action").WriteJSON(c, http.StatusBadRequest)
+			default:
+				zlog.Logger.Error().Err(err).Str("image_id", id.String()).Str("action", action).Msg("failed to resolve processed path")
+				response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	img, info, err := h.storage.Open(c.Request.Context(), path)
+	if err == nil {
+		cacheControl := h.opts.CacheControlDerivative
+		if action == "" {
+			cacheControl = h.opts.CacheControlOriginal
+		}
+		serve(c, img, info, cacheControl)
+		return
+	}
+
+	if !errors.Is(err, domain.ErrObjectNotFound) {
+		zlog.Logger.Error().Err(err).Str("path", path).Msg("failed to load image from storage")
+		response.Error("storage unavailable, try again later").WriteJSON(c, http.StatusServiceUnavailable)
+		return
+	}
+
+	if action == "" {
+		zlog.Logger.Error().Err(err).Str("path", path).Msg("original image is missing in storage")
+		response.Error("image not found").WriteJSON(c, http.StatusNotFound)
+		return
+	}
+
+	if action == string(domain.Variants) && params.Width > 0 {
+		h.variantStatus(c, id, originalPath, path, params.Preset)
+		return
+	}
+
+	h.processedStatus(c, id, path)
+}
+
+// variantStatus отвечает, почему версии из набора нет в хранилище. Ширины больше оригинала
+// обработчик пропускает, поэтому версия ищется в сохранённом манифесте: если набор готов,
+// а версии в нём нет — это ошибка запроса, иначе статус задачи variants.
+func (h *Handler) variantStatus(c *ginext.Context, imageID uuid.UUID, originalPath, variantPath, preset string) {
+	manifestPath, err := h.task.ProcessedPath(c.Request.Context(), originalPath, string(domain.Variants), dto.TaskParams{Preset: preset})
+	if err != nil {
+		zlog.Logger.Error().Err(err).Str("image_id", imageID.String()).Msg("failed to resolve variants manifest path")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	file, err := h.storage.Load(c.Request.Context(), manifestPath)
+	if err != nil {
+		if errors.Is(err, domain.ErrObjectNotFound) {
+			h.processedStatus(c, imageID, manifestPath)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("path", manifestPath).Msg("failed to load variants manifest from storage")
+		response.Error("storage unavailable, try again later").WriteJSON(c, http.StatusServiceUnavailable)
+		return
+	}
+	defer file.Close()
+
+	var manifest domain.VariantManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		zlog.Logger.Error().Err(err).Str("path", manifestPath).Msg("failed to decode variants manifest")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	if !slices.ContainsFunc(manifest.Variants, func(v domain.Variant) bool { return v.Path == variantPath }) {
+		response.Error("width is not available for this image: it exceeds the original width").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	// Версия есть в манифесте, но пропала из хранилища
+	h.processedStatus(c, imageID, manifestPath)
+}
+
+// processedStatus отвечает, почему обработанного изображения ещё нет, по последней задаче для него.
+func (h *Handler) processedStatus(c *ginext.Context, imageID uuid.UUID, path string) {
+	task, err := h.task.GetLatestTask(c.Request.Context(), imageID, path)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			response.Error("processed image was not requested").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("image_id", imageID.String()).Str("path", path).Msg("failed to get latest task")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	switch domain.TaskStatus(task.Status) {
+	case domain.Failed:
+		response.Raw(c, http.StatusUnprocessableEntity, ginext.H{
+			"task_id":        task.ID,
+			"status":         task.Status,
+			"failure_reason": task.FailureReason,
+		})
+	case domain.Completed:
+		// Задача завершилась, но результата в хранилище нет — его нужно запросить заново
+		response.Raw(c, http.StatusNotFound, ginext.H{
+			"task_id": task.ID,
+			"status":  task.Status,
+			"error":   "processed image is missing, enqueue the task again",
+		})
+	default:
+		response.Raw(c, http.StatusAccepted, ginext.H{"task_id": task.ID, "status": task.Status})
+	}
+}
+
+// GET /image/:id/variants?preset=
+// Возвращает манифест набора версий: ссылки на каждую версию (подписанные, если включена подпись),
+// размеры и srcset по форматам. Пока задача variants не завершена, отвечает её статусом.
+// Ссылки выдаются так же, как в SignURL, поэтому маршрут доступен только с токеном выдачи ссылок.
+func (h *Handler) GetVariants(c *ginext.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	preset := c.Query("preset")
+	if preset == "" {
+		response.Error("preset is required").WriteJSON(c, http.StatusBadRequest)
 		return
 	}
 
@@ -94,28 +335,351 @@ func (h *Handler) GetImage(c *ginext.Context) {
 		return
 	}
 
-	if action != "" {
-		original := path
-		path = utils.BuildProcessedPath(original, action)
+	manifestPath, err := h.task.ProcessedPath(c.Request.Context(), path, string(domain.Variants), dto.TaskParams{Preset: preset})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTaskParams) {
+			response.Error(err.Error()).WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("image_id", id.String()).Msg("failed to resolve variants manifest path")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
 	}
 
-	img, err := h.storage.Load(c.Request.Context(), path)
+	file, err := h.storage.Load(c.Request.Context(), manifestPath)
+	if err != nil {
+		if errors.Is(err, domain.ErrObjectNotFound) {
+			h.processedStatus(c, id, manifestPath)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("path", manifestPath).Msg("failed to load variants manifest from storage")
+		response.Error("storage unavailable, try again later").WriteJSON(c, http.StatusServiceUnavailable)
+		return
+	}
+	defer file.Close()
+
+	var manifest domain.VariantManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		zlog.Logger.Error().Err(err).Str("path", manifestPath).Msg("failed to decode variants manifest")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	// Ссылки ведут на GET /image/:id этого же префикса маршрутов
+	imagePath := strings.TrimSuffix(c.Request.URL.Path, "/variants")
+	result := dto.VariantManifest{
+		ImageID:  id,
+		Preset:   manifest.Preset,
+		Variants: make([]dto.Variant, 0, len(manifest.Variants)),
+		Srcset:   make(map[string]string),
+	}
+	for _, v := range manifest.Variants {
+		query := url.Values{
+			"processed": {string(domain.Variants)},
+			"preset":    {preset},
+			"width":     {strconv.Itoa(v.TargetWidth)},
+			"format":    {string(v.Format)},
+		}
+		if h.signer != nil {
+			var expiresAt time.Time
+			query, expiresAt = h.signer.Sign(imagePath, query, h.opts.SignTTL)
+			result.ExpiresAt = &expiresAt
+		}
+		link := buildURL(imagePath, query)
+
+		result.Variants = append(result.Variants, dto.Variant{
+			URL:    link,
+			Width:  v.Width,
+			Height: v.Height,
+			Format: string(v.Format),
+			Size:   v.Size,
+		})
+
+		candidate := fmt.Sprintf("%s %dw", link, v.Width)
+		if srcset := result.Srcset[string(v.Format)]; srcset != "" {
+			candidate = srcset + ", " + candidate
+		}
+		result.Srcset[string(v.Format)] = candidate
+	}
+
+	response.Raw(c, http.StatusOK, result)
+}
+
+// GET /image/:id/transform?w=&h=&fit=&fmt=&q=
+func (h *Handler) Transform(c *ginext.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	var params dto.TransformParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		response.Error("invalid transform params").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	path, err := h.transformer.Transform(c.Request.Context(), id, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidTaskParams):
+			response.Error(err.Error()).WriteJSON(c, http.StatusBadRequest)
+			return
+		case errors.Is(err, domain.ErrImageNotFound):
+			response.Error("image not found").WriteJSON(c, http.StatusNotFound)
+			return
+		case errors.Is(err, domain.ErrUndecodableImage):
+			response.Error("image cannot be decoded").WriteJSON(c, http.StatusUnprocessableEntity)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("image_id", id.String()).Msg("failed to transform image")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	img, info, err := h.storage.Open(c.Request.Context(), path)
 	if err != nil {
 		zlog.Logger.Error().Err(err).Str("path", path).Msg("failed to load image from storage")
-		response.Error("image processing in progress").WriteJSON(c, http.StatusAccepted)
+		response.Error("storage unavailable, try again later").WriteJSON(c, http.StatusServiceUnavailable)
+		return
+	}
+
+	serve(c, img, info, h.opts.CacheControlDerivative)
+}
+
+// GET /image/:id/url?target=image|task|preset|transform&ttl=1h
+//
+//	task:      &task_id= — результат задачи этого изображения (GET /task/:id/result)
+//	preset:    &preset= — результат пресета (GET /image/:id?processed=<preset>)
+//	transform: &w=&h=&fit=&filter=&anchor=&fmt=&q=&compression=&colors=&metadata=&keep_gps=
+//
+// Выдаёт подписанную ссылку. Подписываются только проверенные сервером параметры,
+// остальные параметры запроса в ссылку не попадают.
+func (h *Handler) SignURL(c *ginext.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	ttl := h.opts.SignTTL
+	if rawTTL := c.Query("ttl"); rawTTL != "" {
+		ttl, err = time.ParseDuration(rawTTL)
+		if err != nil || ttl <= 0 || ttl > h.opts.SignMaxTTL {
+			response.Error(fmt.Sprintf("ttl must be a positive duration up to %s", h.opts.SignMaxTTL)).WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	originalPath, err := h.image.GetPathByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrImageNotFound) {
+			response.Error("image not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("image_id", id.String()).Msg("failed to get image path")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
 		return
 	}
+
+	// Пути ссылок строятся от пути этого запроса, чтобы учесть префикс группы маршрутов
+	imagePath := strings.TrimSuffix(c.Request.URL.Path, "/url")
+	path, query := imagePath, url.Values{}
+	switch c.Query("target") {
+	case "", "image":
+	case "task":
+		taskID, err := strconv.Atoi(c.Query("task_id"))
+		if err != nil {
+			response.Error("task_id must be a number").WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+		task, err := h.task.GetTaskByID(ctx, taskID)
+		if err != nil && !errors.Is(err, domain.ErrTaskNotFound) {
+			zlog.Logger.Error().Err(err).Int("task_id", taskID).Msg("failed to get task")
+			response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+			return
+		}
+		if err != nil || task.ImageID != id {
+			response.Error("task not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		prefix := strings.TrimSuffix(imagePath, "/image/"+c.Param("id"))
+		path = fmt.Sprintf("%s/task/%d/result", prefix, task.ID)
+	case "preset":
+		preset := c.Query("preset")
+		if domain.IsBuiltinTaskType(preset) {
+			response.Error("preset must be a name of a stored preset").WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+		if _, err := h.task.ProcessedPath(ctx, originalPath, preset, dto.TaskParams{}); err != nil {
+			switch {
+			case errors.Is(err, domain.ErrPresetNotFound):
+				response.Error("preset not found").WriteJSON(c, http.StatusNotFound)
+			case errors.Is(err, domain.ErrInvalidTaskParams):
+				response.Error(err.Error()).WriteJSON(c, http.StatusBadRequest)
+			default:
+				zlog.Logger.Error().Err(err).Str("image_id", id.String()).Str("preset", preset).Msg("failed to resolve preset")
+				response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+			}
+			return
+		}
+		query.Set("processed", preset)
+	case "transform":
+		var params dto.TransformParams
+		if err := c.ShouldBindQuery(&params); err != nil {
+			response.Error("invalid transform params").WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+		if err := h.transformer.Validate(originalPath, params); err != nil {
+			response.Error(err.Error()).WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+		path += "/transform"
+		query = transformQuery(params)
+	default:
+		response.Error("target must be image, task, preset or transform").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	if h.signer == nil {
+		response.Raw(c, http.StatusOK, ginext.H{"url": buildURL(path, query)})
+		return
+	}
+
+	signed, expiresAt := h.signer.Sign(path, query, ttl)
+	response.Raw(c, http.StatusOK, ginext.H{"url": buildURL(path, signed), "expires_at": expiresAt})
+}
+
+// transformQuery собирает query-строку преобразования из проверенных параметров; пустые значения опускаются.
+func transformQuery(params dto.TransformParams) url.Values {
+	query := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	setInt := func(key string, value int) {
+		if value != 0 {
+			query.Set(key, strconv.Itoa(value))
+		}
+	}
+
+	setInt("w", params.Width)
+	setInt("h", params.Height)
+	set("fit", params.Fit)
+	set("filter", params.Filter)
+	set("anchor", params.Anchor)
+	set("fmt", params.Format)
+	setInt("q", params.Quality)
+	set("compression", params.Compression)
+	setInt("colors", params.Colors)
+	set("metadata", params.Metadata)
+	if params.KeepGPS {
+		query.Set("keep_gps", "true")
+	}
+	return query
+}
+
+func buildURL(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// GET /image/:id/metadata
+func (h *Handler) GetImageMetadata(c *ginext.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	md, err := h.image.GetImageMetadata(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrImageNotFound) {
+			response.Error("image not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("image_id", id.String()).Msg("failed to get image metadata")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusOK, md)
+}
+
+// GET /task/:id/result
+func (h *Handler) GetTaskResult(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error("id must be integer").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.task.GetTaskByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			response.Error("task not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Int("task_id", id).Msg("failed to get task")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	switch domain.TaskStatus(task.Status) {
+	case domain.Completed:
+	case domain.Failed:
+		response.Raw(c, http.StatusUnprocessableEntity, ginext.H{
+			"task_id":        task.ID,
+			"status":         task.Status,
+			"failure_reason": task.FailureReason,
+		})
+		return
+	default:
+		response.Raw(c, http.StatusAccepted, ginext.H{"task_id": task.ID, "status": task.Status})
+		return
+	}
+
+	img, info, err := h.storage.Open(c.Request.Context(), task.ProcessedPath)
+	if err != nil {
+		if errors.Is(err, domain.ErrObjectNotFound) {
+			response.Error("processed image is missing, enqueue the task again").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("path", task.ProcessedPath).Msg("failed to load image from storage")
+		response.Error("storage unavailable, try again later").WriteJSON(c, http.StatusServiceUnavailable)
+		return
+	}
+
+	serve(c, img, info, h.opts.CacheControlDerivative)
+}
+
+// serve отдаёт изображение из хранилища и закрывает его. http.ServeContent
+// по ETag и Last-Modified отвечает 304 на условные запросы, а Range-запросы
+// обслуживает через Seek — хранилище читает только нужный диапазон.
+func serve(c *ginext.Context, img io.ReadSeekCloser, info domain.ObjectInfo, cacheControl string) {
 	defer img.Close()
 
-	filename := filepath.Base(path)
+	filename := filepath.Base(info.Name)
 	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if format, ok := domain.FormatFromExt(filepath.Ext(filename)); ok {
+		contentType = format.ContentType()
+	}
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
 
 	c.Header("Content-Type", contentType)
 	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
-	c.DataFromReader(http.StatusOK, -1, contentType, img, nil)
+	c.Header("Cache-Control", cacheControl)
+	if info.ETag != "" {
+		c.Header("ETag", fmt.Sprintf("%q", info.ETag))
+	}
+
+	http.ServeContent(c.Writer, c.Request, filename, info.LastModified, img)
 }
 
 func (h *Handler) DeleteImage(c *ginext.Context) {
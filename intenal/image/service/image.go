@@ -1,52 +1,100 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/ilam072/image-processor/intenal/image/repo"
+	"github.com/ilam072/image-processor/intenal/storage"
 	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/intenal/types/dto"
+	"github.com/ilam072/image-processor/intenal/utils"
 	"github.com/ilam072/image-processor/pkg/errutils"
 	"io"
 )
 
 type ImageRepo interface {
 	CreateImage(ctx context.Context, image domain.Image) error
+	GetImageByID(ctx context.Context, ID uuid.UUID) (domain.Image, error)
 	GetPathByID(ctx context.Context, ID uuid.UUID) (string, error)
+	MarkImageDeleted(ctx context.Context, ID uuid.UUID) (string, error)
 	DeleteImageByID(ctx context.Context, ID uuid.UUID) error
 }
 
-type ImageStorage interface {
-	SaveImage(ctx context.Context, name string, file io.Reader) error
-	DeleteImage(ctx context.Context, name string) error
+// Opts — ограничения для загружаемых файлов.
+type Opts struct {
+	MaxSize        int64                // максимальный размер файла в байтах
+	MaxWidth       int                  // максимальная ширина в пикселях
+	MaxHeight      int                  // максимальная высота в пикселях
+	MaxPixels      int64                // максимальное количество пикселей (ширина × высота)
+	AllowedFormats []domain.ImageFormat // форматы, которые принимаются на загрузку
 }
 
+const (
+	defaultMaxSize   = 20 << 20
+	defaultMaxPixels = 50_000_000
+)
+
 type Image struct {
 	repo    ImageRepo
-	storage ImageStorage
+	storage storage.Storage
+	opts    Opts
 }
 
-func New(repo ImageRepo, storage ImageStorage) *Image {
-	return &Image{repo: repo, storage: storage}
+func New(repo ImageRepo, storage storage.Storage, opts Opts) *Image {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxSize
+	}
+	if opts.MaxWidth <= 0 {
+		opts.MaxWidth = domain.MaxResizeDimension
+	}
+	if opts.MaxHeight <= 0 {
+		opts.MaxHeight = domain.MaxResizeDimension
+	}
+	if opts.MaxPixels <= 0 {
+		opts.MaxPixels = defaultMaxPixels
+	}
+	if len(opts.AllowedFormats) == 0 {
+		opts.AllowedFormats = []domain.ImageFormat{
+			domain.FormatJPEG, domain.FormatPNG, domain.FormatGIF, domain.FormatBMP, domain.FormatTIFF,
+		}
+	}
+
+	return &Image{repo: repo, storage: storage, opts: opts}
 }
 
-func (i *Image) UploadImage(ctx context.Context, file io.Reader, ext string) (string, error) {
+func (i *Image) UploadImage(ctx context.Context, file io.Reader, filename string) (string, error) {
 	const op = "service.image.Upload"
 
+	// Файл читается целиком: по содержимому определяется формат и считаются метаданные
+	data, err := readLimited(file, i.opts.MaxSize)
+	if err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+
+	format, cfg, err := i.validate(data)
+	if err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+	md := describe(data, filename, format, cfg)
+
+	// Расширение берётся из определённого формата, а не из имени файла
 	ID := uuid.New()
-	name := fmt.Sprintf("original/%s%s", ID.String(), ext)
-	if err := i.storage.SaveImage(ctx, name, file); err != nil {
+	name := fmt.Sprintf("original/%s%s", ID.String(), format.Ext())
+	if err := i.storage.Save(ctx, name, bytes.NewReader(data), md.MimeType); err != nil {
 		return "", errutils.Wrap(op, err)
 	}
 
 	image := domain.Image{
-		ID:   ID,
-		Path: name,
+		ID:       ID,
+		Path:     name,
+		Metadata: md,
 	}
 
 	if err := i.repo.CreateImage(ctx, image); err != nil {
-		_ = i.storage.DeleteImage(ctx, name)
+		_ = i.storage.Delete(ctx, name)
 		return "", errutils.Wrap(op, err)
 	}
 
@@ -67,10 +115,43 @@ func (i *Image) GetPathByID(ctx context.Context, ID uuid.UUID) (string, error) {
 	return path, nil
 }
 
+func (i *Image) GetImageMetadata(ctx context.Context, ID uuid.UUID) (dto.ImageMetadata, error) {
+	const op = "service.image.GetImageMetadata"
+
+	image, err := i.repo.GetImageByID(ctx, ID)
+	if err != nil {
+		if errors.Is(err, repo.ErrImageNotFound) {
+			return dto.ImageMetadata{}, errutils.Wrap(op, domain.ErrImageNotFound)
+		}
+		return dto.ImageMetadata{}, errutils.Wrap(op, err)
+	}
+
+	md := image.Metadata
+	return dto.ImageMetadata{
+		ID:               image.ID,
+		UploadedAt:       image.UploadedAt,
+		OriginalFilename: md.OriginalFilename,
+		MimeType:         md.MimeType,
+		Size:             md.Size,
+		Checksum:         md.Checksum,
+		Width:            md.Width,
+		Height:           md.Height,
+		ColorModel:       md.ColorModel,
+		CameraMake:       md.CameraMake,
+		CameraModel:      md.CameraModel,
+		CapturedAt:       md.CapturedAt,
+		Orientation:      md.Orientation,
+	}, nil
+}
+
+// DeleteImage удаляет изображение вместе со всеми обработанными версиями.
+// Сначала изображение помечается удалённым и его задачи отменяются — после этого
+// новые версии не появятся, — затем удаляются объекты и сама строка.
+// Если удаление объектов прервалось, строка остаётся помеченной и повторный вызов продолжит его.
 func (i *Image) DeleteImage(ctx context.Context, ID uuid.UUID) error {
 	const op = "service.image.Delete"
 
-	path, err := i.repo.GetPathByID(ctx, ID)
+	path, err := i.repo.MarkImageDeleted(ctx, ID)
 	if err != nil {
 		if errors.Is(err, repo.ErrImageNotFound) {
 			return errutils.Wrap(op, domain.ErrImageNotFound)
@@ -78,7 +159,18 @@ func (i *Image) DeleteImage(ctx context.Context, ID uuid.UUID) error {
 		return errutils.Wrap(op, err)
 	}
 
-	if err := i.storage.DeleteImage(ctx, path); err != nil {
+	derivatives, err := i.storage.List(ctx, utils.ProcessedPrefix(path))
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	for _, obj := range derivatives {
+		if err := i.storage.Delete(ctx, obj.Name); err != nil {
+			return errutils.Wrap(op, err)
+		}
+	}
+
+	if err := i.storage.Delete(ctx, path); err != nil {
 		return errutils.Wrap(op, err)
 	}
 
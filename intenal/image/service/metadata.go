@@ -0,0 +1,69 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ilam072/image-processor/intenal/image/metadata"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"image"
+	"image/color"
+)
+
+// describe собирает метаданные оригинала по уже проверенному заголовку (см. validate).
+func describe(data []byte, filename string, format domain.ImageFormat, cfg image.Config) domain.ImageMetadata {
+	sum := sha256.Sum256(data)
+
+	md := domain.ImageMetadata{
+		OriginalFilename: filename,
+		MimeType:         format.ContentType(),
+		Size:             int64(len(data)),
+		Checksum:         hex.EncodeToString(sum[:]),
+		Width:            cfg.Width,
+		Height:           cfg.Height,
+		ColorModel:       colorModelName(cfg.ColorModel),
+	}
+
+	if raw := metadata.Extract(data).EXIF; len(raw) > 0 {
+		if exif, err := metadata.ParseEXIF(raw); err == nil {
+			md.CameraMake = exif.Make
+			md.CameraModel = exif.Model
+			md.CapturedAt = exif.CapturedAt
+			md.Orientation = exif.Orientation
+		}
+	}
+
+	return md
+}
+
+func colorModelName(m color.Model) string {
+	switch m {
+	case color.RGBAModel:
+		return "rgba"
+	case color.RGBA64Model:
+		return "rgba64"
+	case color.NRGBAModel:
+		return "nrgba"
+	case color.NRGBA64Model:
+		return "nrgba64"
+	case color.AlphaModel:
+		return "alpha"
+	case color.Alpha16Model:
+		return "alpha16"
+	case color.GrayModel:
+		return "gray"
+	case color.Gray16Model:
+		return "gray16"
+	case color.YCbCrModel:
+		return "ycbcr"
+	case color.NYCbCrAModel:
+		return "nycbcra"
+	case color.CMYKModel:
+		return "cmyk"
+	}
+
+	if _, ok := m.(color.Palette); ok {
+		return "paletted"
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"slices"
+)
+
+// readLimited читает файл, но не больше maxSize байт.
+func readLimited(file io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", domain.ErrFileTooLarge, maxSize)
+	}
+	if len(data) == 0 {
+		return nil, domain.ErrEmptyFile
+	}
+
+	return data, nil
+}
+
+// validate определяет формат по сигнатуре и проверяет размеры по заголовку
+// через image.DecodeConfig — до декодирования пикселей, чтобы не распаковать
+// "бомбу" на десятки гигапикселей.
+func (i *Image) validate(data []byte) (domain.ImageFormat, image.Config, error) {
+	format, ok := domain.DetectFormat(data)
+	if !ok {
+		return "", image.Config{}, domain.ErrUnsupportedFormat
+	}
+	if !slices.Contains(i.opts.AllowedFormats, format) {
+		return "", image.Config{}, fmt.Errorf("%w: %s is not allowed", domain.ErrUnsupportedFormat, format)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", image.Config{}, fmt.Errorf("%w: %w", domain.ErrUndecodableImage, err)
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return "", image.Config{}, fmt.Errorf("%w: empty %dx%d image", domain.ErrUndecodableImage, cfg.Width, cfg.Height)
+	}
+	if cfg.Width > i.opts.MaxWidth || cfg.Height > i.opts.MaxHeight {
+		return "", image.Config{}, fmt.Errorf("%w: %dx%d is larger than %dx%d",
+			domain.ErrImageTooLarge, cfg.Width, cfg.Height, i.opts.MaxWidth, i.opts.MaxHeight)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > i.opts.MaxPixels {
+		return "", image.Config{}, fmt.Errorf("%w: %d pixels is more than %d",
+			domain.ErrImageTooLarge, int64(cfg.Width)*int64(cfg.Height), i.opts.MaxPixels)
+	}
+
+	return format, cfg, nil
+}
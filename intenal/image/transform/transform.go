@@ -0,0 +1,183 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ilam072/image-processor/intenal/storage"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/intenal/types/dto"
+	"github.com/ilam072/image-processor/intenal/utils"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"github.com/ilam072/image-processor/pkg/singleflight"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type Image interface {
+	GetPathByID(ctx context.Context, ID uuid.UUID) (string, error)
+}
+
+type Processor interface {
+	Pipeline(img io.ReadCloser, ops []domain.Operation) (io.ReadCloser, error)
+}
+
+const defaultMaxConcurrent = 4
+
+// Opts — параметры преобразований на лету.
+type Opts struct {
+	MaxConcurrent int // сколько изображений может обрабатываться одновременно
+}
+
+// Transformer синхронно строит производные изображения и кэширует их в хранилище
+// под ключом, который однозначно определяется нормализованными параметрами.
+type Transformer struct {
+	image     Image
+	storage   storage.Storage
+	processor Processor
+	flight    singleflight.Group[struct{}]
+	slots     chan struct{}
+}
+
+func New(image Image, storage storage.Storage, processor Processor, opts Opts) *Transformer {
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = defaultMaxConcurrent
+	}
+
+	return &Transformer{
+		image:     image,
+		storage:   storage,
+		processor: processor,
+		slots:     make(chan struct{}, opts.MaxConcurrent),
+	}
+}
+
+// Transform гарантирует, что производное изображение есть в хранилище, и возвращает его путь.
+// Если производное уже сохранено, обработки нет; одновременные запросы одного
+// и того же производного объединяются в одну обработку.
+func (t *Transformer) Transform(ctx context.Context, ID uuid.UUID, params dto.TransformParams) (string, error) {
+	const op = "transform.Transform"
+
+	originalPath, err := t.image.GetPathByID(ctx, ID)
+	if err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+
+	ops, err := operations(params, originalPath)
+	if err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+	path := DerivativePath(originalPath, ops)
+
+	if _, err := t.storage.Stat(ctx, path); err == nil {
+		return path, nil
+	}
+
+	// Обработка не должна прерываться, если первый из ожидающих клиентов отключился
+	renderCtx := context.WithoutCancel(ctx)
+	_, err, _ = t.flight.Do(path, func() (struct{}, error) {
+		return struct{}{}, t.render(renderCtx, ID, originalPath, path, ops)
+	})
+	if err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+
+	return path, nil
+}
+
+// Validate проверяет параметры преобразования, не обращаясь к хранилищу.
+func (t *Transformer) Validate(originalPath string, params dto.TransformParams) error {
+	const op = "transform.Validate"
+
+	if _, err := operations(params, originalPath); err != nil {
+		return errutils.Wrap(op, err)
+	}
+	return nil
+}
+
+func (t *Transformer) render(ctx context.Context, ID uuid.UUID, originalPath, path string, ops []domain.Operation) error {
+	const op = "transform.render"
+
+	// Пока ждали своей очереди, производное мог сохранить предыдущий вызов
+	if _, err := t.storage.Stat(ctx, path); err == nil {
+		return nil
+	}
+
+	t.slots <- struct{}{}
+	defer func() { <-t.slots }()
+
+	original, err := t.storage.Load(ctx, originalPath)
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+	defer original.Close()
+
+	processed, err := t.processor.Pipeline(original, ops)
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+	defer processed.Close()
+
+	output, _ := domain.PipelineOutput(ops)
+	if err := t.storage.Save(ctx, path, processed, output.Format.ContentType()); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	// Если изображение удалили во время обработки, производное не должно остаться в хранилище
+	if _, err := t.image.GetPathByID(ctx, ID); errors.Is(err, domain.ErrImageNotFound) {
+		_ = t.storage.Delete(ctx, path)
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+// operations переводит параметры запроса в нормализованный конвейер: resize (если задан размер) и encode.
+func operations(params dto.TransformParams, originalPath string) ([]domain.Operation, error) {
+	var ops []domain.Operation
+
+	if params.Width != 0 || params.Height != 0 {
+		resize, err := domain.NewResizeParams(params.Width, params.Height, params.Fit, params.Filter, params.Anchor)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, domain.Operation{Type: domain.OpResize, OperationParams: domain.OperationParams{Resize: &resize}})
+	}
+
+	output, err := domain.NewOutputParams(domain.EncodeParams{
+		Format:      domain.ImageFormat(params.Format),
+		Quality:     params.Quality,
+		Compression: domain.PNGCompression(params.Compression),
+		Colors:      params.Colors,
+		Metadata:    domain.MetadataMode(params.Metadata),
+		KeepGPS:     params.KeepGPS,
+	}, originalPath)
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, domain.Operation{Type: domain.OpEncode, OperationParams: domain.OperationParams{Encode: &output}})
+
+	return domain.NewPipeline(ops)
+}
+
+// Имя производного без расширения: uuid_t_<PipelineKey>
+var derivativeName = regexp.MustCompile(`^[0-9a-f-]{36}_t_[0-9a-f]{12}$`)
+
+// IsDerivativePath сообщает, построен ли путь через DerivativePath. У производных нет строк
+// в tasks, поэтому сборщик мусора узнаёт их по имени.
+func IsDerivativePath(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "processed/") && derivativeName.MatchString(strings.TrimSuffix(base, path.Ext(base)))
+}
+
+// DerivativePath формирует ключ производного изображения.
+// Например: original/uuid.jpg → processed/uuid_t_3f2a9c0b51de.png
+func DerivativePath(originalPath string, ops []domain.Operation) string {
+	path := utils.BuildProcessedPath(originalPath, "t_"+domain.PipelineKey(ops))
+	if output, ok := domain.PipelineOutput(ops); ok {
+		path = utils.ReplaceExt(path, output.Format.Ext())
+	}
+	return path
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"github.com/ilam072/image-processor/intenal/response"
+	"github.com/wb-go/wbf/ginext"
+	"net/http"
+	"strings"
+)
+
+// IssuerToken пропускает только запросы с токеном выдачи ссылок в заголовке
+// Authorization: Bearer <token>. Без настроенных токенов отклоняет все запросы.
+func IssuerToken(tokens []string) ginext.HandlerFunc {
+	known := make([][]byte, 0, len(tokens))
+	for _, token := range tokens {
+		if token = strings.TrimSpace(token); token != "" {
+			known = append(known, []byte(token))
+		}
+	}
+
+	return func(c *ginext.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			response.Error("authorization is required").WriteJSON(c, http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+
+		for _, k := range known {
+			if subtle.ConstantTimeCompare([]byte(token), k) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		response.Error("invalid token").WriteJSON(c, http.StatusForbidden)
+		c.Abort()
+	}
+}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"errors"
+	"github.com/ilam072/image-processor/intenal/response"
+	"github.com/ilam072/image-processor/intenal/signer"
+	"github.com/wb-go/wbf/ginext"
+	"net/http"
+	"net/url"
+)
+
+type Verifier interface {
+	Verify(path string, query url.Values) error
+}
+
+// SignedURL пропускает только запросы с действительной подписью URL.
+// Если v == nil, подпись не требуется — так бывает только при явном SIGNING_DISABLED=true.
+func SignedURL(v Verifier) ginext.HandlerFunc {
+	return func(c *ginext.Context) {
+		if v == nil {
+			c.Next()
+			return
+		}
+
+		err := v.Verify(c.Request.URL.Path, c.Request.URL.Query())
+		switch {
+		case err == nil:
+			c.Next()
+			return
+		case errors.Is(err, signer.ErrMissingSignature):
+			response.Error("signature is required").WriteJSON(c, http.StatusUnauthorized)
+		case errors.Is(err, signer.ErrExpired):
+			response.Error("signed url has expired").WriteJSON(c, http.StatusForbidden)
+		default:
+			response.Error("invalid signature").WriteJSON(c, http.StatusForbidden)
+		}
+
+		c.Abort()
+	}
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ilam072/image-processor/intenal/preset/repo"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"github.com/wb-go/wbf/dbpg"
+)
+
+const presetColumns = `name, pipeline, output, created_at, updated_at`
+
+type PresetRepo struct {
+	db *dbpg.DB
+}
+
+func New(db *dbpg.DB) *PresetRepo {
+	return &PresetRepo{db: db}
+}
+
+func (r *PresetRepo) CreatePreset(ctx context.Context, preset domain.Preset) (domain.Preset, error) {
+	const op = "repo.preset.Create"
+
+	pipeline, output, err := marshalPreset(preset)
+	if err != nil {
+		return domain.Preset{}, errutils.Wrap(op, err)
+	}
+
+	query := `
+		INSERT INTO presets (name, pipeline, output)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING ` + presetColumns
+
+	created, err := scanPreset(r.db.QueryRowContext(ctx, query, preset.Name, pipeline, output))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Preset{}, errutils.Wrap(op, repo.ErrPresetExists)
+		}
+		return domain.Preset{}, errutils.Wrap(op, err)
+	}
+
+	return created, nil
+}
+
+func (r *PresetRepo) GetPresetByName(ctx context.Context, name string) (domain.Preset, error) {
+	const op = "repo.preset.GetPresetByName"
+
+	query := `SELECT ` + presetColumns + ` FROM presets WHERE name = $1`
+
+	preset, err := scanPreset(r.db.QueryRowContext(ctx, query, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Preset{}, errutils.Wrap(op, repo.ErrPresetNotFound)
+		}
+		return domain.Preset{}, errutils.Wrap(op, err)
+	}
+
+	return preset, nil
+}
+
+func (r *PresetRepo) ListPresets(ctx context.Context) ([]domain.Preset, error) {
+	const op = "repo.preset.ListPresets"
+
+	query := `SELECT ` + presetColumns + ` FROM presets ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	var presets []domain.Preset
+	for rows.Next() {
+		preset, err := scanPreset(rows)
+		if err != nil {
+			return nil, errutils.Wrap(op, err)
+		}
+		presets = append(presets, preset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return presets, nil
+}
+
+func (r *PresetRepo) UpdatePreset(ctx context.Context, preset domain.Preset) (domain.Preset, error) {
+	const op = "repo.preset.UpdatePreset"
+
+	pipeline, output, err := marshalPreset(preset)
+	if err != nil {
+		return domain.Preset{}, errutils.Wrap(op, err)
+	}
+
+	query := `
+		UPDATE presets
+		SET pipeline = $2, output = $3, updated_at = NOW()
+		WHERE name = $1
+		RETURNING ` + presetColumns
+
+	updated, err := scanPreset(r.db.QueryRowContext(ctx, query, preset.Name, pipeline, output))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Preset{}, errutils.Wrap(op, repo.ErrPresetNotFound)
+		}
+		return domain.Preset{}, errutils.Wrap(op, err)
+	}
+
+	return updated, nil
+}
+
+func (r *PresetRepo) DeletePreset(ctx context.Context, name string) error {
+	const op = "repo.preset.DeletePreset"
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM presets WHERE name = $1`, name)
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+	if rows == 0 {
+		return errutils.Wrap(op, repo.ErrPresetNotFound)
+	}
+
+	return nil
+}
+
+// marshalPreset кодирует конвейер и настройки вывода в JSON; без настроек вывода — NULL.
+func marshalPreset(preset domain.Preset) ([]byte, []byte, error) {
+	pipeline, err := json.Marshal(preset.Pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	if preset.Output == nil {
+		return pipeline, nil, nil
+	}
+
+	output, err := json.Marshal(preset.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pipeline, output, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPreset(s scanner) (domain.Preset, error) {
+	var (
+		preset   domain.Preset
+		pipeline []byte
+		output   []byte
+	)
+
+	if err := s.Scan(&preset.Name, &pipeline, &output, &preset.CreatedAt, &preset.UpdatedAt); err != nil {
+		return domain.Preset{}, err
+	}
+
+	if err := json.Unmarshal(pipeline, &preset.Pipeline); err != nil {
+		return domain.Preset{}, err
+	}
+	if output != nil {
+		if err := json.Unmarshal(output, &preset.Output); err != nil {
+			return domain.Preset{}, err
+		}
+	}
+
+	return preset, nil
+}
@@ -0,0 +1,8 @@
+package repo
+
+import "errors"
+
+var (
+	ErrPresetNotFound = errors.New("preset not found")
+	ErrPresetExists   = errors.New("preset already exists")
+)
@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"github.com/ilam072/image-processor/intenal/response"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/intenal/types/dto"
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+	"net/http"
+)
+
+type Preset interface {
+	CreatePreset(ctx context.Context, params dto.PresetParams) (dto.Preset, error)
+	GetPreset(ctx context.Context, name string) (dto.Preset, error)
+	ListPresets(ctx context.Context) ([]dto.Preset, error)
+	UpdatePreset(ctx context.Context, name string, params dto.PresetParams) (dto.Preset, error)
+	DeletePreset(ctx context.Context, name string) error
+}
+
+type Handler struct {
+	preset Preset
+}
+
+func NewPresetHandler(preset Preset) *Handler {
+	return &Handler{preset: preset}
+}
+
+// POST /presets
+// {"name": "card", "operations": [{"op": "resize", "resize": {...}}, {"op": "sharpen"}], "output": {"format": "jpeg", "quality": 85}}
+func (h *Handler) CreatePreset(c *ginext.Context) {
+	var params dto.PresetParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		response.Error("invalid preset body").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	preset, err := h.preset.CreatePreset(c.Request.Context(), params)
+	if err != nil {
+		if h.writeError(c, err) {
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("preset", params.Name).Msg("failed to create preset")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusCreated, preset)
+}
+
+// GET /presets
+func (h *Handler) ListPresets(c *ginext.Context) {
+	presets, err := h.preset.ListPresets(c.Request.Context())
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("failed to list presets")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusOK, ginext.H{"presets": presets})
+}
+
+// GET /presets/:name
+func (h *Handler) GetPreset(c *ginext.Context) {
+	name := c.Param("name")
+
+	preset, err := h.preset.GetPreset(c.Request.Context(), name)
+	if err != nil {
+		if h.writeError(c, err) {
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("preset", name).Msg("failed to get preset")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusOK, preset)
+}
+
+// PUT /presets/:name
+// {"operations": [...], "output": {...}}
+func (h *Handler) UpdatePreset(c *ginext.Context) {
+	name := c.Param("name")
+
+	var params dto.PresetParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		response.Error("invalid preset body").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	preset, err := h.preset.UpdatePreset(c.Request.Context(), name, params)
+	if err != nil {
+		if h.writeError(c, err) {
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("preset", name).Msg("failed to update preset")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusOK, preset)
+}
+
+// DELETE /presets/:name
+func (h *Handler) DeletePreset(c *ginext.Context) {
+	name := c.Param("name")
+
+	if err := h.preset.DeletePreset(c.Request.Context(), name); err != nil {
+		if h.writeError(c, err) {
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("preset", name).Msg("failed to delete preset")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success("preset deleted successfully").WriteJSON(c, http.StatusOK)
+}
+
+// writeError отвечает на ошибки, вызванные запросом клиента. Возвращает false, если ошибка внутренняя.
+func (h *Handler) writeError(c *ginext.Context, err error) bool {
+	switch {
+	case errors.Is(err, domain.ErrInvalidPreset), errors.Is(err, domain.ErrInvalidTaskParams):
+		response.Error(err.Error()).WriteJSON(c, http.StatusBadRequest)
+	case errors.Is(err, domain.ErrPresetNotFound):
+		response.Error("preset not found").WriteJSON(c, http.StatusNotFound)
+	case errors.Is(err, domain.ErrPresetExists):
+		response.Error("preset already exists").WriteJSON(c, http.StatusConflict)
+	default:
+		return false
+	}
+	return true
+}
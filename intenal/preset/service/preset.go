@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/ilam072/image-processor/intenal/preset/repo"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/intenal/types/dto"
+	"github.com/ilam072/image-processor/pkg/errutils"
+)
+
+type PresetRepo interface {
+	CreatePreset(ctx context.Context, preset domain.Preset) (domain.Preset, error)
+	GetPresetByName(ctx context.Context, name string) (domain.Preset, error)
+	ListPresets(ctx context.Context) ([]domain.Preset, error)
+	UpdatePreset(ctx context.Context, preset domain.Preset) (domain.Preset, error)
+	DeletePreset(ctx context.Context, name string) error
+}
+
+// PresetCache — кэш пресетов, который нужно сбросить после изменения пресета.
+type PresetCache interface {
+	InvalidatePreset(name string)
+}
+
+type Preset struct {
+	repo  PresetRepo
+	cache PresetCache
+}
+
+func New(repo PresetRepo, cache PresetCache) *Preset {
+	return &Preset{repo: repo, cache: cache}
+}
+
+func (p *Preset) CreatePreset(ctx context.Context, params dto.PresetParams) (dto.Preset, error) {
+	const op = "service.preset.CreatePreset"
+
+	preset, err := domain.NewPreset(domain.Preset{Name: params.Name, Pipeline: params.Operations, Output: params.Output})
+	if err != nil {
+		return dto.Preset{}, errutils.Wrap(op, err)
+	}
+
+	created, err := p.repo.CreatePreset(ctx, preset)
+	if err != nil {
+		if errors.Is(err, repo.ErrPresetExists) {
+			return dto.Preset{}, errutils.Wrap(op, domain.ErrPresetExists)
+		}
+		return dto.Preset{}, errutils.Wrap(op, err)
+	}
+	p.cache.InvalidatePreset(created.Name)
+
+	return domainToDto(created), nil
+}
+
+func (p *Preset) GetPreset(ctx context.Context, name string) (dto.Preset, error) {
+	const op = "service.preset.GetPreset"
+
+	preset, err := p.repo.GetPresetByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, repo.ErrPresetNotFound) {
+			return dto.Preset{}, errutils.Wrap(op, domain.ErrPresetNotFound)
+		}
+		return dto.Preset{}, errutils.Wrap(op, err)
+	}
+
+	return domainToDto(preset), nil
+}
+
+func (p *Preset) ListPresets(ctx context.Context) ([]dto.Preset, error) {
+	const op = "service.preset.ListPresets"
+
+	presets, err := p.repo.ListPresets(ctx)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	result := make([]dto.Preset, 0, len(presets))
+	for _, preset := range presets {
+		result = append(result, domainToDto(preset))
+	}
+
+	return result, nil
+}
+
+// UpdatePreset заменяет конвейер и настройки вывода пресета. Уже поставленные задачи
+// выполняются по старому конвейеру, новые сохраняют результат по другому пути.
+func (p *Preset) UpdatePreset(ctx context.Context, name string, params dto.PresetParams) (dto.Preset, error) {
+	const op = "service.preset.UpdatePreset"
+
+	preset, err := domain.NewPreset(domain.Preset{Name: name, Pipeline: params.Operations, Output: params.Output})
+	if err != nil {
+		return dto.Preset{}, errutils.Wrap(op, err)
+	}
+
+	updated, err := p.repo.UpdatePreset(ctx, preset)
+	if err != nil {
+		if errors.Is(err, repo.ErrPresetNotFound) {
+			return dto.Preset{}, errutils.Wrap(op, domain.ErrPresetNotFound)
+		}
+		return dto.Preset{}, errutils.Wrap(op, err)
+	}
+	p.cache.InvalidatePreset(updated.Name)
+
+	return domainToDto(updated), nil
+}
+
+// DeletePreset удаляет пресет. Сохранённые результаты остаются и удаляются вместе с изображениями.
+func (p *Preset) DeletePreset(ctx context.Context, name string) error {
+	const op = "service.preset.DeletePreset"
+
+	if err := p.repo.DeletePreset(ctx, name); err != nil {
+		if errors.Is(err, repo.ErrPresetNotFound) {
+			return errutils.Wrap(op, domain.ErrPresetNotFound)
+		}
+		return errutils.Wrap(op, err)
+	}
+	p.cache.InvalidatePreset(name)
+
+	return nil
+}
+
+func domainToDto(preset domain.Preset) dto.Preset {
+	return dto.Preset{
+		Name:       preset.Name,
+		Operations: preset.Pipeline,
+		Output:     preset.Output,
+		CreatedAt:  preset.CreatedAt,
+		UpdatedAt:  preset.UpdatedAt,
+	}
+}
@@ -0,0 +1,158 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/queue"
+	"github.com/segmentio/kafka-go"
+	wbfkafka "github.com/wb-go/wbf/kafka"
+	"io"
+	"sync"
+)
+
+type Publisher struct {
+	w *wbfkafka.Producer
+}
+
+func NewPublisher(brokers []string, topic string) *Publisher {
+	return &Publisher{w: wbfkafka.NewProducer(brokers, topic)}
+}
+
+func (p *Publisher) Publish(ctx context.Context, key, value []byte) error {
+	return p.w.Send(ctx, key, value)
+}
+
+func (p *Publisher) Close() error {
+	return p.w.Close()
+}
+
+// Consumer допускает подтверждение сообщений в произвольном порядке: offset партиции
+// коммитится только когда обработаны все предыдущие сообщения этой партиции,
+// поэтому после падения ни одно неподтверждённое сообщение не будет пропущено.
+type Consumer struct {
+	r       *wbfkafka.Consumer
+	tracker *offsetTracker
+
+	// commitMu упорядочивает коммиты, чтобы offset партиции только возрастал;
+	// committed — последний закоммиченный offset по партициям
+	commitMu  sync.Mutex
+	committed map[int]int64
+}
+
+func NewConsumer(brokers []string, topic string, groupID string) *Consumer {
+	return &Consumer{
+		r:         wbfkafka.NewConsumer(brokers, topic, groupID),
+		tracker:   newOffsetTracker(),
+		committed: make(map[int]int64),
+	}
+}
+
+func (c *Consumer) Consume(ctx context.Context) (queue.Message, error) {
+	msg, err := c.r.Fetch(ctx)
+	if err != nil {
+		// Закрытый reader возвращает io.EOF
+		if errors.Is(err, io.EOF) {
+			return queue.Message{}, queue.ErrClosed
+		}
+		return queue.Message{}, err
+	}
+
+	c.tracker.add(msg)
+
+	return queue.Message{Key: msg.Key, Value: msg.Value, Ref: msg}, nil
+}
+
+func (c *Consumer) Commit(ctx context.Context, msg queue.Message) error {
+	km, ok := msg.Ref.(kafka.Message)
+	if !ok {
+		return fmt.Errorf("message was not consumed from kafka")
+	}
+
+	ready, ok := c.tracker.done(km)
+	if !ok {
+		return nil
+	}
+
+	// Трекер не блокируется на время сетевого вызова; коммит меньшего offset'а,
+	// дождавшийся очереди после большего, пропускается
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	if last, ok := c.committed[ready.Partition]; ok && ready.Offset <= last {
+		return nil
+	}
+	if err := c.r.Commit(ctx, ready); err != nil {
+		return err
+	}
+	c.committed[ready.Partition] = ready.Offset
+
+	return nil
+}
+
+func (c *Consumer) Close() error {
+	return c.r.Close()
+}
+
+// offsetTracker хранит offset'ы сообщений, взятых в работу, по партициям.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	pending  []int64                 // offset'ы в работе, по возрастанию
+	finished map[int64]kafka.Message // обработанные, но ещё не закоммиченные
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[int]*partitionOffsets)}
+}
+
+func (t *offsetTracker) add(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[msg.Partition]
+	if !ok {
+		p = &partitionOffsets{finished: make(map[int64]kafka.Message)}
+		t.partitions[msg.Partition] = p
+	}
+
+	// Reader отдаёт сообщения партиции по возрастанию offset'а
+	p.pending = append(p.pending, msg.Offset)
+}
+
+// done отмечает сообщение обработанным. Возвращает последнее сообщение непрерывного
+// обработанного префикса партиции, если его можно закоммитить.
+func (t *offsetTracker) done(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[msg.Partition]
+	if !ok {
+		return kafka.Message{}, false
+	}
+
+	p.finished[msg.Offset] = msg
+
+	var (
+		ready kafka.Message
+		found bool
+	)
+	for len(p.pending) > 0 {
+		m, ok := p.finished[p.pending[0]]
+		if !ok {
+			break
+		}
+		delete(p.finished, p.pending[0])
+		p.pending = p.pending[1:]
+		ready, found = m, true
+	}
+
+	if len(p.pending) == 0 {
+		p.pending = nil
+	}
+
+	return ready, found
+}
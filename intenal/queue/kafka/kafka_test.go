@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"github.com/segmentio/kafka-go"
+	"testing"
+)
+
+func msg(partition int, offset int64) kafka.Message {
+	return kafka.Message{Partition: partition, Offset: offset}
+}
+
+func TestOffsetTrackerInOrder(t *testing.T) {
+	tr := newOffsetTracker()
+	tr.add(msg(0, 10))
+	tr.add(msg(0, 11))
+
+	ready, ok := tr.done(msg(0, 10))
+	if !ok || ready.Offset != 10 {
+		t.Fatalf("done(10) = %d, %v; want 10, true", ready.Offset, ok)
+	}
+	ready, ok = tr.done(msg(0, 11))
+	if !ok || ready.Offset != 11 {
+		t.Fatalf("done(11) = %d, %v; want 11, true", ready.Offset, ok)
+	}
+}
+
+func TestOffsetTrackerOutOfOrder(t *testing.T) {
+	tr := newOffsetTracker()
+	for _, offset := range []int64{1, 2, 3, 4} {
+		tr.add(msg(0, offset))
+	}
+
+	// Пока 1 в работе, коммитить нечего
+	if _, ok := tr.done(msg(0, 3)); ok {
+		t.Fatal("done(3) before 1 is finished returned a commit")
+	}
+	if _, ok := tr.done(msg(0, 2)); ok {
+		t.Fatal("done(2) before 1 is finished returned a commit")
+	}
+
+	// Завершение 1 открывает непрерывный префикс 1..3
+	ready, ok := tr.done(msg(0, 1))
+	if !ok || ready.Offset != 3 {
+		t.Fatalf("done(1) = %d, %v; want 3, true", ready.Offset, ok)
+	}
+
+	ready, ok = tr.done(msg(0, 4))
+	if !ok || ready.Offset != 4 {
+		t.Fatalf("done(4) = %d, %v; want 4, true", ready.Offset, ok)
+	}
+	if p := tr.partitions[0]; len(p.pending) != 0 || len(p.finished) != 0 {
+		t.Errorf("partition state not drained: pending=%v finished=%d", p.pending, len(p.finished))
+	}
+}
+
+func TestOffsetTrackerPartitionsAreIndependent(t *testing.T) {
+	tr := newOffsetTracker()
+	tr.add(msg(0, 5))
+	tr.add(msg(1, 7))
+	tr.add(msg(0, 6))
+
+	// Незавершённое сообщение партиции 0 не задерживает партицию 1
+	ready, ok := tr.done(msg(1, 7))
+	if !ok || ready.Partition != 1 || ready.Offset != 7 {
+		t.Fatalf("done(p1, 7) = %+v, %v", ready, ok)
+	}
+	if _, ok := tr.done(msg(0, 6)); ok {
+		t.Fatal("done(p0, 6) before 5 is finished returned a commit")
+	}
+	ready, ok = tr.done(msg(0, 5))
+	if !ok || ready.Partition != 0 || ready.Offset != 6 {
+		t.Fatalf("done(p0, 5) = %+v, %v; want offset 6", ready, ok)
+	}
+}
+
+func TestOffsetTrackerUnknownPartition(t *testing.T) {
+	tr := newOffsetTracker()
+	if _, ok := tr.done(msg(3, 1)); ok {
+		t.Fatal("done for a partition that was never consumed returned a commit")
+	}
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"github.com/ilam072/image-processor/intenal/queue"
+	"sync"
+)
+
+// Broker — очередь в памяти процесса для тестов и локального запуска.
+// Сообщения не переживают перезапуск, Commit ничего не делает.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]chan queue.Message
+	size   int
+}
+
+func NewBroker(size int) *Broker {
+	if size <= 0 {
+		size = 1024
+	}
+	return &Broker{topics: make(map[string]chan queue.Message), size: size}
+}
+
+func (b *Broker) topic(name string) chan queue.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch, ok := b.topics[name]
+	if !ok {
+		ch = make(chan queue.Message, b.size)
+		b.topics[name] = ch
+	}
+	return ch
+}
+
+func (b *Broker) Publisher(topic string) *Publisher {
+	return &Publisher{ch: b.topic(topic)}
+}
+
+func (b *Broker) Consumer(topic string) *Consumer {
+	return &Consumer{ch: b.topic(topic), closed: make(chan struct{})}
+}
+
+type Publisher struct {
+	ch chan queue.Message
+}
+
+func (p *Publisher) Publish(ctx context.Context, key, value []byte) error {
+	select {
+	case p.ch <- queue.Message{Key: key, Value: value}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Publisher) Close() error {
+	return nil
+}
+
+type Consumer struct {
+	ch        chan queue.Message
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *Consumer) Consume(ctx context.Context) (queue.Message, error) {
+	select {
+	case msg := <-c.ch:
+		return msg, nil
+	case <-c.closed:
+		return queue.Message{}, queue.ErrClosed
+	case <-ctx.Done():
+		return queue.Message{}, ctx.Err()
+	}
+}
+
+func (c *Consumer) Commit(ctx context.Context, msg queue.Message) error {
+	return nil
+}
+
+func (c *Consumer) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/queue"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"github.com/wb-go/wbf/dbpg"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollInterval      = time.Second
+	defaultVisibilityTimeout = 5 * time.Minute
+)
+
+type Publisher struct {
+	db    *dbpg.DB
+	topic string
+}
+
+func NewPublisher(db *dbpg.DB, topic string) *Publisher {
+	return &Publisher{db: db, topic: topic}
+}
+
+func (p *Publisher) Publish(ctx context.Context, key, value []byte) error {
+	const op = "queue.postgres.Publish"
+
+	query := `INSERT INTO queue_messages (topic, key, value) VALUES ($1, $2, $3)`
+
+	if _, err := p.db.ExecContext(ctx, query, p.topic, key, value); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (p *Publisher) Close() error {
+	return nil
+}
+
+type ConsumerOpts struct {
+	PollInterval      time.Duration // пауза между опросами пустой очереди
+	VisibilityTimeout time.Duration // через сколько неподтверждённое сообщение будет выдано снова
+}
+
+// Consumer забирает сообщения через FOR UPDATE SKIP LOCKED, так что несколько воркеров
+// не получают одно и то же сообщение. Выданное сообщение скрыто на VisibilityTimeout,
+// пока обработчик продлевает скрытие через Extend; Commit удаляет его из очереди.
+type Consumer struct {
+	db        *dbpg.DB
+	topic     string
+	opts      ConsumerOpts
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewConsumer(db *dbpg.DB, topic string, opts ConsumerOpts) *Consumer {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	return &Consumer{db: db, topic: topic, opts: opts, closed: make(chan struct{})}
+}
+
+func (c *Consumer) Consume(ctx context.Context) (queue.Message, error) {
+	const op = "queue.postgres.Consume"
+
+	query := `
+		UPDATE queue_messages
+		SET locked_until = NOW() + $2 * INTERVAL '1 millisecond', deliveries = deliveries + 1
+		WHERE id = (
+			SELECT id
+			FROM queue_messages
+			WHERE topic = $1 AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, key, value
+	`
+
+	for {
+		var (
+			id  int64
+			msg queue.Message
+		)
+		err := c.db.Master.QueryRowContext(ctx, query, c.topic, c.opts.VisibilityTimeout.Milliseconds()).
+			Scan(&id, &msg.Key, &msg.Value)
+		if err == nil {
+			msg.Ref = id
+			return msg, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return queue.Message{}, errutils.Wrap(op, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return queue.Message{}, ctx.Err()
+		case <-c.closed:
+			return queue.Message{}, queue.ErrClosed
+		case <-time.After(c.opts.PollInterval):
+		}
+	}
+}
+
+func (c *Consumer) Commit(ctx context.Context, msg queue.Message) error {
+	const op = "queue.postgres.Commit"
+
+	id, ok := msg.Ref.(int64)
+	if !ok {
+		return errutils.Wrap(op, fmt.Errorf("message was not consumed from postgres"))
+	}
+
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM queue_messages WHERE id = $1`, id); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+// Extend продлевает скрытие выданного сообщения: не меньше чем на ttl от текущего момента
+// и не меньше VisibilityTimeout. Вызывается, пока сообщение в обработке.
+func (c *Consumer) Extend(ctx context.Context, msg queue.Message, ttl time.Duration) error {
+	const op = "queue.postgres.Extend"
+
+	id, ok := msg.Ref.(int64)
+	if !ok {
+		return errutils.Wrap(op, fmt.Errorf("message was not consumed from postgres"))
+	}
+
+	query := `UPDATE queue_messages SET locked_until = NOW() + $2 * INTERVAL '1 millisecond' WHERE id = $1`
+
+	timeout := max(ttl, c.opts.VisibilityTimeout)
+	if _, err := c.db.ExecContext(ctx, query, id, timeout.Milliseconds()); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (c *Consumer) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
@@ -0,0 +1,31 @@
+// Package queue описывает очередь задач, не зависящую от брокера.
+// Реализации: kafka, postgres (FOR UPDATE SKIP LOCKED) и memory.
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrClosed = errors.New("queue is closed")
+
+// Message — сообщение очереди.
+type Message struct {
+	Key   []byte
+	Value []byte
+	// Ref — данные адаптера, необходимые для Commit (сообщение Kafka, id строки и т.п.)
+	Ref any
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, key, value []byte) error
+	Close() error
+}
+
+// Consumer выдаёт сообщения по одному. Commit подтверждает обработку сообщения;
+// неподтверждённые сообщения будут доставлены повторно.
+type Consumer interface {
+	Consume(ctx context.Context) (Message, error)
+	Commit(ctx context.Context, msg Message) error
+	Close() error
+}
@@ -0,0 +1,131 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры подписи в query-строке
+const (
+	ParamExpires   = "expires"
+	ParamKeyID     = "kid"
+	ParamSignature = "sig"
+)
+
+var (
+	ErrMissingSignature = errors.New("signature is missing")
+	ErrInvalidSignature = errors.New("signature is invalid")
+	ErrExpired          = errors.New("signed url has expired")
+)
+
+// Signer подписывает и проверяет URL через HMAC-SHA256.
+// Подписывается активным ключом, а проверяется любым из известных — так ключи
+// можно ротировать: новый ключ добавляется и делается активным, старый удаляется,
+// когда истекут выданные им ссылки.
+type Signer struct {
+	keys      map[string][]byte
+	activeKID string
+	now       func() time.Time
+}
+
+// New создаёт Signer. keys — идентификатор ключа (kid) → секрет.
+func New(keys map[string][]byte, activeKID string) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	if _, ok := keys[activeKID]; !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", activeKID)
+	}
+
+	return &Signer{keys: keys, activeKID: activeKID, now: time.Now}, nil
+}
+
+// ParseKeys разбирает ключи из конфигурации в формате "kid:secret".
+func ParseKeys(raw []string) (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(raw))
+	for _, item := range raw {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kid, secret, ok := strings.Cut(item, ":")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("signing key must be in kid:secret format")
+		}
+		keys[kid] = []byte(secret)
+	}
+
+	return keys, nil
+}
+
+// Sign возвращает копию query с добавленными expires, kid и sig.
+// Подпись покрывает путь и все параметры запроса, поэтому их нельзя изменить.
+func (s *Signer) Sign(path string, query url.Values, ttl time.Duration) (url.Values, time.Time) {
+	signed := url.Values{}
+	for key, values := range query {
+		if key == ParamSignature {
+			continue
+		}
+		signed[key] = append([]string(nil), values...)
+	}
+
+	expiresAt := s.now().Add(ttl).Truncate(time.Second)
+	signed.Set(ParamExpires, strconv.FormatInt(expiresAt.Unix(), 10))
+	signed.Set(ParamKeyID, s.activeKID)
+	signed.Set(ParamSignature, sign(s.keys[s.activeKID], path, signed))
+
+	return signed, expiresAt
+}
+
+// Verify проверяет подпись и срок действия URL.
+func (s *Signer) Verify(path string, query url.Values) error {
+	sig := query.Get(ParamSignature)
+	if sig == "" {
+		return ErrMissingSignature
+	}
+
+	key, ok := s.keys[query.Get(ParamKeyID)]
+	if !ok {
+		return fmt.Errorf("%w: unknown key", ErrInvalidSignature)
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(sign(key, path, query))) {
+		return ErrInvalidSignature
+	}
+
+	// Срок проверяется после подписи: expires тоже подписан и не мог быть изменён
+	expires, err := strconv.ParseInt(query.Get(ParamExpires), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed expires", ErrInvalidSignature)
+	}
+	if s.now().Unix() > expires {
+		return ErrExpired
+	}
+
+	return nil
+}
+
+// sign считает HMAC от пути и отсортированных параметров запроса без самой подписи.
+func sign(key []byte, path string, query url.Values) string {
+	payload := url.Values{}
+	for k, v := range query {
+		if k != ParamSignature {
+			payload[k] = v
+		}
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(payload.Encode()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,144 @@
+package signer
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T, keys map[string][]byte, active string, now time.Time) *Signer {
+	t.Helper()
+
+	s, err := New(keys, active)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := newTestSigner(t, map[string][]byte{"k1": []byte("secret")}, "k1", now)
+
+	const path = "/api/image/3f2a/transform"
+	signed, expiresAt := s.Sign(path, url.Values{"w": {"320"}, "fmt": {"webp"}}, time.Hour)
+
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expiresAt = %v, want %v", expiresAt, now.Add(time.Hour))
+	}
+	if signed.Get(ParamKeyID) != "k1" || signed.Get(ParamSignature) == "" {
+		t.Fatalf("signed query = %v", signed)
+	}
+	if err := s.Verify(path, signed); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Порядок параметров в URL на подпись не влияет
+	parsed, err := url.ParseQuery(signed.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(path, parsed); err != nil {
+		t.Errorf("Verify after re-encoding: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := newTestSigner(t, map[string][]byte{"k1": []byte("secret")}, "k1", now)
+
+	const path = "/api/image/3f2a/transform"
+	signed, _ := s.Sign(path, url.Values{"w": {"320"}}, time.Minute)
+
+	tests := []struct {
+		name   string
+		path   string
+		modify func(q url.Values)
+		want   error
+	}{
+		{name: "changed param", path: path, modify: func(q url.Values) { q.Set("w", "4000") }, want: ErrInvalidSignature},
+		{name: "added param", path: path, modify: func(q url.Values) { q.Set("h", "100") }, want: ErrInvalidSignature},
+		{name: "removed param", path: path, modify: func(q url.Values) { q.Del("w") }, want: ErrInvalidSignature},
+		{name: "extended expires", path: path, modify: func(q url.Values) { q.Set(ParamExpires, "9999999999") }, want: ErrInvalidSignature},
+		{name: "other path", path: "/api/image/other/transform", modify: func(url.Values) {}, want: ErrInvalidSignature},
+		{name: "unknown kid", path: path, modify: func(q url.Values) { q.Set(ParamKeyID, "k9") }, want: ErrInvalidSignature},
+		{name: "missing signature", path: path, modify: func(q url.Values) { q.Del(ParamSignature) }, want: ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{}
+			for k, v := range signed {
+				q[k] = append([]string(nil), v...)
+			}
+			tt.modify(q)
+
+			if err := s.Verify(tt.path, q); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := newTestSigner(t, map[string][]byte{"k1": []byte("secret")}, "k1", now)
+
+	signed, expiresAt := s.Sign("/api/image/3f2a", nil, time.Minute)
+
+	s.now = func() time.Time { return expiresAt }
+	if err := s.Verify("/api/image/3f2a", signed); err != nil {
+		t.Errorf("Verify at expiry: %v", err)
+	}
+
+	s.now = func() time.Time { return expiresAt.Add(time.Second) }
+	if err := s.Verify("/api/image/3f2a", signed); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify after expiry = %v, want ErrExpired", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	const path = "/api/image/3f2a"
+
+	old := newTestSigner(t, map[string][]byte{"k1": []byte("old")}, "k1", now)
+	signedOld, _ := old.Sign(path, nil, time.Hour)
+
+	// Новый ключ активен, старый ещё известен: обе ссылки проверяются
+	rotated := newTestSigner(t, map[string][]byte{"k1": []byte("old"), "k2": []byte("new")}, "k2", now)
+	signedNew, _ := rotated.Sign(path, nil, time.Hour)
+	if signedNew.Get(ParamKeyID) != "k2" {
+		t.Fatalf("kid = %q, want k2", signedNew.Get(ParamKeyID))
+	}
+	for name, q := range map[string]url.Values{"old": signedOld, "new": signedNew} {
+		if err := rotated.Verify(path, q); err != nil {
+			t.Errorf("Verify %s link during rotation: %v", name, err)
+		}
+	}
+
+	// Старый ключ удалён: его ссылки больше не действуют
+	retired := newTestSigner(t, map[string][]byte{"k2": []byte("new")}, "k2", now)
+	if err := retired.Verify(path, signedOld); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify old link after retirement = %v, want ErrInvalidSignature", err)
+	}
+	if err := retired.Verify(path, signedNew); err != nil {
+		t.Errorf("Verify new link after retirement: %v", err)
+	}
+
+	// Ключ с тем же kid, но другим секретом не принимает чужую подпись
+	forged := newTestSigner(t, map[string][]byte{"k1": []byte("other")}, "k1", now)
+	if err := forged.Verify(path, signedOld); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with different secret = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil, "k1"); err == nil {
+		t.Error("New without keys succeeded")
+	}
+	if _, err := New(map[string][]byte{"k1": []byte("s")}, "k2"); err == nil {
+		t.Error("New with unknown active key succeeded")
+	}
+}
@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/config"
+	"github.com/ilam072/image-processor/intenal/storage"
+	"github.com/ilam072/image-processor/intenal/storage/local"
+	"github.com/ilam072/image-processor/intenal/storage/memory"
+	storageminio "github.com/ilam072/image-processor/intenal/storage/minio"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// New создаёт хранилище для STORAGE_BACKEND. Для minio бакет создаётся, если его ещё нет.
+func New(ctx context.Context, cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Storage.Backend {
+	case "", "minio":
+		return newMinio(ctx, cfg.Minio)
+	case "local":
+		return local.New(cfg.Storage.LocalDir)
+	case "memory":
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
+func newMinio(ctx context.Context, cfg config.MinioConfig) (storage.Storage, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.RootUser, cfg.RootPassword, ""),
+		Secure: cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate minio client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.BucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if bucket exists: %w", err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.BucketName, minio.MakeBucketOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket: %w", err)
+		}
+	}
+
+	return storageminio.New(client, cfg.BucketName), nil
+}
@@ -0,0 +1,215 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Префикс временных файлов: объект появляется под своим именем только после полной записи
+const tmpPrefix = ".tmp-"
+
+// Storage хранит объекты файлами в каталоге root, имя объекта — относительный путь.
+// Content-Type определяется по расширению, ETag — по размеру и времени изменения файла.
+type Storage struct {
+	root string
+}
+
+func New(root string) (*Storage, error) {
+	const op = "storage.local.New"
+
+	if root == "" {
+		return nil, errutils.Wrap(op, errors.New("storage directory is not set"))
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return &Storage{root: root}, nil
+}
+
+func (s *Storage) Save(ctx context.Context, name string, file io.Reader, contentType string) error {
+	const op = "storage.local.Save"
+
+	p, err := s.path(name)
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), tmpPrefix+"*")
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, file); err != nil {
+		_ = tmp.Close()
+		return errutils.Wrap(op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Load(ctx context.Context, name string) (io.ReadCloser, error) {
+	const op = "storage.local.Load"
+
+	f, _, err := s.open(name)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return f, nil
+}
+
+func (s *Storage) Open(ctx context.Context, name string) (io.ReadSeekCloser, domain.ObjectInfo, error) {
+	const op = "storage.local.Open"
+
+	f, info, err := s.open(name)
+	if err != nil {
+		return nil, domain.ObjectInfo{}, errutils.Wrap(op, err)
+	}
+
+	return f, info, nil
+}
+
+func (s *Storage) Stat(ctx context.Context, name string) (domain.ObjectInfo, error) {
+	const op = "storage.local.Stat"
+
+	p, err := s.path(name)
+	if err != nil {
+		return domain.ObjectInfo{}, errutils.Wrap(op, err)
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		return domain.ObjectInfo{}, errutils.Wrap(op, mapError(err))
+	}
+
+	return toObjectInfo(name, fi), nil
+}
+
+func (s *Storage) Delete(ctx context.Context, name string) error {
+	const op = "storage.local.Delete"
+
+	p, err := s.path(name)
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) List(ctx context.Context, prefix string) ([]domain.ObjectInfo, error) {
+	const op = "storage.local.List"
+
+	var objects []domain.ObjectInfo
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tmpPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, toObjectInfo(name, fi))
+		return nil
+	})
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return objects, nil
+}
+
+func (s *Storage) open(name string) (*os.File, domain.ObjectInfo, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, domain.ObjectInfo{}, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, domain.ObjectInfo{}, mapError(err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, domain.ObjectInfo{}, err
+	}
+	if fi.IsDir() {
+		_ = f.Close()
+		return nil, domain.ObjectInfo{}, domain.ErrObjectNotFound
+	}
+
+	return f, toObjectInfo(name, fi), nil
+}
+
+// path переводит имя объекта в путь внутри root. Имена с "..", абсолютные и неканонические
+// отклоняются, чтобы нельзя было выйти за пределы root.
+func (s *Storage) path(name string) (string, error) {
+	if name == "." || !fs.ValidPath(name) || strings.HasPrefix(path.Base(name), tmpPrefix) {
+		return "", fmt.Errorf("invalid object name %q", name)
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(name)), nil
+}
+
+// mapError отличает отсутствие файла от остальных ошибок файловой системы.
+func mapError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", domain.ErrObjectNotFound, err)
+	}
+	return err
+}
+
+func toObjectInfo(name string, fi fs.FileInfo) domain.ObjectInfo {
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return domain.ObjectInfo{
+		Name:         name,
+		Size:         fi.Size(),
+		ETag:         fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size()),
+		ContentType:  contentType,
+		LastModified: fi.ModTime(),
+	}
+}
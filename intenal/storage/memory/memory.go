@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage — хранилище в памяти процесса для тестов и локального запуска.
+// Объекты не переживают перезапуск.
+type Storage struct {
+	mu      sync.RWMutex
+	objects map[string]object
+}
+
+type object struct {
+	data []byte
+	info domain.ObjectInfo
+}
+
+func New() *Storage {
+	return &Storage{objects: make(map[string]object)}
+}
+
+func (s *Storage) Save(ctx context.Context, name string, file io.Reader, contentType string) error {
+	const op = "storage.memory.Save"
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	sum := md5.Sum(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[name] = object{
+		data: data,
+		info: domain.ObjectInfo{
+			Name:         name,
+			Size:         int64(len(data)),
+			ETag:         hex.EncodeToString(sum[:]),
+			ContentType:  contentType,
+			LastModified: time.Now(),
+		},
+	}
+
+	return nil
+}
+
+func (s *Storage) Load(ctx context.Context, name string) (io.ReadCloser, error) {
+	const op = "storage.memory.Load"
+
+	obj, err := s.get(name)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+func (s *Storage) Open(ctx context.Context, name string) (io.ReadSeekCloser, domain.ObjectInfo, error) {
+	const op = "storage.memory.Open"
+
+	obj, err := s.get(name)
+	if err != nil {
+		return nil, domain.ObjectInfo{}, errutils.Wrap(op, err)
+	}
+
+	return nopSeekCloser{bytes.NewReader(obj.data)}, obj.info, nil
+}
+
+func (s *Storage) Stat(ctx context.Context, name string) (domain.ObjectInfo, error) {
+	const op = "storage.memory.Stat"
+
+	obj, err := s.get(name)
+	if err != nil {
+		return domain.ObjectInfo{}, errutils.Wrap(op, err)
+	}
+
+	return obj.info, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, name)
+	return nil
+}
+
+func (s *Storage) List(ctx context.Context, prefix string) ([]domain.ObjectInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var objects []domain.ObjectInfo
+	for name, obj := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			objects = append(objects, obj.info)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+
+	return objects, nil
+}
+
+func (s *Storage) get(name string) (object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	obj, ok := s.objects[name]
+	if !ok {
+		return object{}, fmt.Errorf("%w: %s", domain.ErrObjectNotFound, name)
+	}
+	return obj, nil
+}
+
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
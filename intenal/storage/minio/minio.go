@@ -0,0 +1,129 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/pkg/errutils"
+	"github.com/minio/minio-go/v7"
+	"io"
+	"strings"
+)
+
+// Storage хранит объекты в бакете MinIO (S3).
+type Storage struct {
+	mc     *minio.Client
+	bucket string
+}
+
+func New(client *minio.Client, bucket string) *Storage {
+	return &Storage{mc: client, bucket: bucket}
+}
+
+func (s *Storage) Save(ctx context.Context, name string, file io.Reader, contentType string) error {
+	const op = "storage.minio.Save"
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	_, err := s.mc.PutObject(ctx, s.bucket, name, file, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Load(ctx context.Context, name string) (io.ReadCloser, error) {
+	const op = "storage.minio.Load"
+
+	img, err := s.mc.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	// GetObject ленивый — Stat выполняет запрос, чтобы ошибка (нет объекта, недоступен MinIO)
+	// вернулась здесь, а не при первом чтении
+	if _, err := img.Stat(); err != nil {
+		_ = img.Close()
+		return nil, errutils.Wrap(op, mapError(err))
+	}
+
+	return img, nil
+}
+
+// Open открывает объект для чтения с произвольным доступом: Seek у minio.Object
+// переводит следующее чтение в ranged GET, что используется для Range-запросов.
+func (s *Storage) Open(ctx context.Context, name string) (io.ReadSeekCloser, domain.ObjectInfo, error) {
+	const op = "storage.minio.Open"
+
+	obj, err := s.mc.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, domain.ObjectInfo{}, errutils.Wrap(op, err)
+	}
+
+	info, err := obj.Stat()
+	if err != nil {
+		_ = obj.Close()
+		return nil, domain.ObjectInfo{}, errutils.Wrap(op, mapError(err))
+	}
+
+	return obj, toObjectInfo(info), nil
+}
+
+func (s *Storage) Stat(ctx context.Context, name string) (domain.ObjectInfo, error) {
+	const op = "storage.minio.Stat"
+
+	info, err := s.mc.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return domain.ObjectInfo{}, errutils.Wrap(op, mapError(err))
+	}
+
+	return toObjectInfo(info), nil
+}
+
+// List возвращает все объекты с заданным префиксом.
+func (s *Storage) List(ctx context.Context, prefix string) ([]domain.ObjectInfo, error) {
+	const op = "storage.minio.List"
+
+	var objects []domain.ObjectInfo
+	for obj := range s.mc.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, errutils.Wrap(op, obj.Err)
+		}
+		objects = append(objects, toObjectInfo(obj))
+	}
+
+	return objects, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, name string) error {
+	const op = "storage.minio.Delete"
+
+	if err := s.mc.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{}); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+// mapError отличает отсутствие объекта от недоступности хранилища.
+func mapError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return fmt.Errorf("%w: %w", domain.ErrObjectNotFound, err)
+	}
+	return err
+}
+
+func toObjectInfo(info minio.ObjectInfo) domain.ObjectInfo {
+	return domain.ObjectInfo{
+		Name:         info.Key,
+		Size:         info.Size,
+		ETag:         strings.Trim(info.ETag, `"`),
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}
+}
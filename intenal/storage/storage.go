@@ -0,0 +1,23 @@
+// Package storage описывает хранилище объектов, не зависящее от backend'а.
+// Реализации: minio, local (файловая система) и memory.
+package storage
+
+import (
+	"context"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"io"
+)
+
+// Storage хранит объекты по именам вида original/uuid.jpg.
+// Отсутствующий объект в Load, Open и Stat — ошибка, оборачивающая domain.ErrObjectNotFound;
+// Delete отсутствующего объекта ошибкой не считается.
+type Storage interface {
+	Save(ctx context.Context, name string, file io.Reader, contentType string) error
+	Load(ctx context.Context, name string) (io.ReadCloser, error)
+	// Open открывает объект для чтения с произвольным доступом (Range-запросы)
+	Open(ctx context.Context, name string) (io.ReadSeekCloser, domain.ObjectInfo, error)
+	Stat(ctx context.Context, name string) (domain.ObjectInfo, error)
+	Delete(ctx context.Context, name string) error
+	// List возвращает все объекты, имя которых начинается с prefix
+	List(ctx context.Context, prefix string) ([]domain.ObjectInfo, error)
+}
@@ -0,0 +1,184 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ilam072/image-processor/intenal/storage"
+	"github.com/ilam072/image-processor/intenal/storage/local"
+	"github.com/ilam072/image-processor/intenal/storage/memory"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Контракт Storage одинаков для всех backend'ов; minio проверяется только с живым сервером.
+func TestStorageContract(t *testing.T) {
+	backends := map[string]func(t *testing.T) storage.Storage{
+		"memory": func(t *testing.T) storage.Storage {
+			return memory.New()
+		},
+		"local": func(t *testing.T) storage.Storage {
+			s, err := local.New(t.TempDir())
+			if err != nil {
+				t.Fatalf("local.New: %v", err)
+			}
+			return s
+		},
+	}
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			testContract(t, newStorage(t))
+		})
+	}
+}
+
+func testContract(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	const name = "original/a.jpg"
+
+	if err := s.Save(ctx, name, strings.NewReader("first"), "image/jpeg"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := s.Save(ctx, name, strings.NewReader("hello"), "image/jpeg"); err != nil {
+		t.Fatalf("Save overwrite: %v", err)
+	}
+	if err := s.Save(ctx, "processed/a_resize.png", strings.NewReader("png"), "image/png"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	t.Run("Load", func(t *testing.T) {
+		r, err := s.Load(ctx, name)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if string(data) != "hello" {
+			t.Errorf("Load = %q, want %q", data, "hello")
+		}
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		r, info, err := s.Open(ctx, name)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		defer r.Close()
+
+		if info.Name != name || info.Size != 5 || info.ETag == "" {
+			t.Errorf("Open info = %+v", info)
+		}
+		if _, err := r.Seek(2, io.SeekStart); err != nil {
+			t.Fatalf("Seek: %v", err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if string(data) != "llo" {
+			t.Errorf("read after Seek = %q, want %q", data, "llo")
+		}
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		info, err := s.Stat(ctx, name)
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if info.Name != name || info.Size != 5 || info.ContentType != "image/jpeg" {
+			t.Errorf("Stat = %+v", info)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		objects, err := s.List(ctx, "original/")
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(objects) != 1 || objects[0].Name != name {
+			t.Errorf("List(original/) = %+v, want only %s", objects, name)
+		}
+
+		objects, err = s.List(ctx, "")
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(objects) != 2 {
+			t.Errorf("List() returned %d objects, want 2", len(objects))
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		const missing = "original/missing.jpg"
+
+		if _, err := s.Load(ctx, missing); !errors.Is(err, domain.ErrObjectNotFound) {
+			t.Errorf("Load missing: err = %v, want ErrObjectNotFound", err)
+		}
+		if _, _, err := s.Open(ctx, missing); !errors.Is(err, domain.ErrObjectNotFound) {
+			t.Errorf("Open missing: err = %v, want ErrObjectNotFound", err)
+		}
+		if _, err := s.Stat(ctx, missing); !errors.Is(err, domain.ErrObjectNotFound) {
+			t.Errorf("Stat missing: err = %v, want ErrObjectNotFound", err)
+		}
+		if err := s.Delete(ctx, missing); err != nil {
+			t.Errorf("Delete missing: %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := s.Delete(ctx, name); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := s.Stat(ctx, name); !errors.Is(err, domain.ErrObjectNotFound) {
+			t.Errorf("Stat after Delete: err = %v, want ErrObjectNotFound", err)
+		}
+
+		objects, err := s.List(ctx, "original/")
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(objects) != 0 {
+			t.Errorf("List after Delete = %+v, want empty", objects)
+		}
+	})
+}
+
+func TestLocalRejectsPathTraversal(t *testing.T) {
+	ctx := context.Background()
+	parent := t.TempDir()
+	s, err := local.New(filepath.Join(parent, "root"))
+	if err != nil {
+		t.Fatalf("local.New: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"../secret.txt", "original/../../secret.txt", "/etc/passwd", "", ".", "original//a.jpg"} {
+		if err := s.Save(ctx, name, strings.NewReader("x"), "text/plain"); err == nil {
+			t.Errorf("Save(%q) succeeded, want error", name)
+		}
+		if _, err := s.Load(ctx, name); err == nil || errors.Is(err, domain.ErrObjectNotFound) {
+			t.Errorf("Load(%q): err = %v, want invalid name error", name, err)
+		}
+		if _, err := s.Stat(ctx, name); err == nil || errors.Is(err, domain.ErrObjectNotFound) {
+			t.Errorf("Stat(%q): err = %v, want invalid name error", name, err)
+		}
+		if err := s.Delete(ctx, name); err == nil {
+			t.Errorf("Delete(%q) succeeded, want error", name)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(parent, "secret.txt"))
+	if err != nil || string(data) != "secret" {
+		t.Errorf("file outside root changed: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,71 @@
+package outbox
+
+import (
+	"context"
+	"github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+)
+
+type Repo interface {
+	ProcessOutbox(ctx context.Context, limit int, send func(ctx context.Context, taskID int) error) (int, error)
+}
+
+type Producer interface {
+	Produce(ctx context.Context, taskID int) error
+}
+
+type Opts struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Relay публикует записи outbox в очередь задач (at-least-once):
+// запись отмечается отправленной только после успешной публикации.
+type Relay struct {
+	repo Repo
+	p    Producer
+	opts Opts
+}
+
+func New(repo Repo, p Producer, opts Opts) *Relay {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	return &Relay{repo: repo, p: p, opts: opts}
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Пока пачки полные — outbox не разобран, продолжаем без ожидания
+		for {
+			sent, err := r.repo.ProcessOutbox(ctx, r.opts.BatchSize, r.p.Produce)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				zlog.Logger.Error().Err(err).Int("sent", sent).Msg("failed to relay outbox")
+				break
+			}
+			if sent < r.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
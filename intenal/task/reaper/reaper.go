@@ -0,0 +1,82 @@
+package reaper
+
+import (
+	"context"
+	"github.com/wb-go/wbf/zlog"
+	"time"
+)
+
+const (
+	defaultInterval    = 30 * time.Second
+	defaultLeaseTTL    = time.Minute
+	defaultMaxAttempts = 5
+	defaultBatchSize   = 100
+)
+
+type Repo interface {
+	ReapExpiredTasks(ctx context.Context, leaseTTL time.Duration, maxAttempts, limit int) (requeued int, failed int, err error)
+}
+
+type Opts struct {
+	Interval    time.Duration
+	LeaseTTL    time.Duration
+	MaxAttempts int
+	BatchSize   int
+}
+
+// Reaper периодически ищет задачи, воркер которых перестал продлевать аренду,
+// и возвращает их в очередь либо помечает failed после MaxAttempts попыток.
+type Reaper struct {
+	repo Repo
+	opts Opts
+}
+
+func New(repo Repo, opts Opts) *Reaper {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = defaultLeaseTTL
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	return &Reaper{repo: repo, opts: opts}
+}
+
+func (r *Reaper) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.reap(ctx)
+		}
+	}
+}
+
+func (r *Reaper) reap(ctx context.Context) {
+	for {
+		requeued, failed, err := r.repo.ReapExpiredTasks(ctx, r.opts.LeaseTTL, r.opts.MaxAttempts, r.opts.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				zlog.Logger.Error().Err(err).Msg("failed to reap expired tasks")
+			}
+			return
+		}
+
+		if requeued > 0 || failed > 0 {
+			zlog.Logger.Warn().Int("requeued", requeued).Int("failed", failed).Msg("reaped tasks with expired lease")
+		}
+
+		if requeued+failed < r.opts.BatchSize {
+			return
+		}
+	}
+}
@@ -3,13 +3,18 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"github.com/google/uuid"
 	"github.com/ilam072/image-processor/intenal/task/repo"
 	"github.com/ilam072/image-processor/intenal/types/domain"
 	"github.com/ilam072/image-processor/pkg/errutils"
 	"github.com/wb-go/wbf/dbpg"
+	"time"
 )
 
+const taskColumns = `id, image_id, processed_path, type, status, params, COALESCE(failure_class, ''), COALESCE(failure_reason, ''), created_at, started_at, finished_at`
+
 type TaskRepo struct {
 	db *dbpg.DB
 }
@@ -18,20 +23,33 @@ func New(db *dbpg.DB) *TaskRepo {
 	return &TaskRepo{db: db}
 }
 
+// CreateTask создаёт задачу и запись в outbox в одной транзакции.
 func (r *TaskRepo) CreateTask(ctx context.Context, task domain.Task) (int, error) {
 	const op = "repo.task.Create"
 
+	params, err := json.Marshal(task.Params)
+	if err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	query := `
-		INSERT INTO tasks (image_id, processed_path, type, status)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO tasks (image_id, processed_path, type, status, params)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id
 	`
 
-	row := r.db.QueryRowContext(ctx, query,
+	row := tx.QueryRowContext(ctx, query,
 		task.ImageID,
 		task.ProcessedPath,
 		task.Type,
 		task.Status,
+		params,
 	)
 
 	var ID int
@@ -39,28 +57,114 @@ func (r *TaskRepo) CreateTask(ctx context.Context, task domain.Task) (int, error
 		return 0, errutils.Wrap(op, err)
 	}
 
+	if _, err := tx.ExecContext(ctx, `INSERT INTO task_outbox (task_id) VALUES ($1)`, ID); err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
 	return ID, nil
 }
 
+// ProcessOutbox блокирует до limit неотправленных записей outbox (SKIP LOCKED, чтобы несколько
+// инстансов не отправляли одно и то же), вызывает send для каждой по порядку и отмечает
+// отправленные. На первой ошибке отправки обработка останавливается, уже отправленные сохраняются.
+func (r *TaskRepo) ProcessOutbox(ctx context.Context, limit int, send func(ctx context.Context, taskID int) error) (int, error) {
+	const op = "repo.task.ProcessOutbox"
+
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		SELECT id, task_id
+		FROM task_outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	type entry struct {
+		id     int64
+		taskID int
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.taskID); err != nil {
+			_ = rows.Close()
+			return 0, errutils.Wrap(op, err)
+		}
+		entries = append(entries, e)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	sent := 0
+	var sendErr error
+	for _, e := range entries {
+		if sendErr = send(ctx, e.taskID); sendErr != nil {
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE task_outbox SET sent_at = NOW() WHERE id = $1`, e.id); err != nil {
+			return 0, errutils.Wrap(op, err)
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	if sendErr != nil {
+		return sent, errutils.Wrap(op, sendErr)
+	}
+
+	return sent, nil
+}
+
 func (r *TaskRepo) GetTaskByID(ctx context.Context, id int) (domain.Task, error) {
 	const op = "repo.task.GetTaskByID"
 
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1`
+
+	task, err := scanTask(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Task{}, errutils.Wrap(op, repo.ErrTaskNotFound)
+		}
+		return domain.Task{}, errutils.Wrap(op, err)
+	}
+
+	return task, nil
+}
+
+// GetLatestTaskByProcessedPath возвращает последнюю задачу изображения с указанным путём результата.
+func (r *TaskRepo) GetLatestTaskByProcessedPath(ctx context.Context, imageID uuid.UUID, processedPath string) (domain.Task, error) {
+	const op = "repo.task.GetLatestTaskByProcessedPath"
+
 	query := `
-		SELECT id, image_id, processed_path, type, status, created_at
+		SELECT ` + taskColumns + `
 		FROM tasks
-		WHERE id = $1
+		WHERE image_id = $1 AND processed_path = $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
 	`
 
-	var task domain.Task
-	row := r.db.QueryRowContext(ctx, query, id)
-	if err := row.Scan(
-		&task.ID,
-		&task.ImageID,
-		&task.ProcessedPath,
-		&task.Type,
-		&task.Status,
-		&task.CreatedAt,
-	); err != nil {
+	task, err := scanTask(r.db.QueryRowContext(ctx, query, imageID, processedPath))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Task{}, errutils.Wrap(op, repo.ErrTaskNotFound)
 		}
@@ -70,10 +174,96 @@ func (r *TaskRepo) GetTaskByID(ctx context.Context, id int) (domain.Task, error)
 	return task, nil
 }
 
+func (r *TaskRepo) ListTasksByImageID(ctx context.Context, imageID uuid.UUID, limit, offset int) ([]domain.Task, error) {
+	const op = "repo.task.ListTasksByImageID"
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE image_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, imageID, limit, offset)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	tasks := make([]domain.Task, 0, limit)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, errutils.Wrap(op, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return tasks, nil
+}
+
+// ListTasksPage возвращает до limit задач с id больше afterID.
+func (r *TaskRepo) ListTasksPage(ctx context.Context, afterID int, limit int) ([]domain.Task, error) {
+	const op = "repo.task.ListTasksPage"
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	var tasks []domain.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, errutils.Wrap(op, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return tasks, nil
+}
+
+func (r *TaskRepo) CountTasksByImageID(ctx context.Context, imageID uuid.UUID) (int, error) {
+	const op = "repo.task.CountTasksByImageID"
+
+	query := `SELECT COUNT(*) FROM tasks WHERE image_id = $1`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, imageID).Scan(&count); err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	return count, nil
+}
+
+// UpdateTaskStatus меняет статус задачи. Отменённая задача не меняется:
+// воркер мог ещё не узнать, что изображение удалено.
 func (r *TaskRepo) UpdateTaskStatus(ctx context.Context, ID int, status domain.TaskStatus) error {
 	const op = "repo.task.UpdateTaskStatus"
 
-	query := `UPDATE tasks SET status = $1 WHERE id = $2`
+	query := `
+		UPDATE tasks
+		SET status = $1,
+		    started_at = CASE WHEN $1 = 'processing'::task_status THEN NOW() ELSE started_at END,
+		    finished_at = CASE WHEN $1 IN ('completed'::task_status, 'failed'::task_status) THEN NOW() ELSE finished_at END
+		WHERE id = $2 AND status <> 'canceled'
+	`
 
 	if _, err := r.db.ExecContext(ctx, query, status, ID); err != nil {
 		return errutils.Wrap(op, err)
@@ -81,3 +271,229 @@ func (r *TaskRepo) UpdateTaskStatus(ctx context.Context, ID int, status domain.T
 
 	return nil
 }
+
+// RenewLease продлевает аренду задачи, пока она не завершена.
+func (r *TaskRepo) RenewLease(ctx context.Context, ID int, ttl time.Duration) error {
+	const op = "repo.task.RenewLease"
+
+	query := `
+		UPDATE tasks
+		SET lease_until = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id = $1 AND status IN ('queued', 'processing', 'retrying')
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, ID, ttl.Milliseconds()); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+// ReapExpiredTasks находит до limit задач в processing/retrying с истёкшей арендой.
+// Задачи, у которых попыток меньше maxAttempts, возвращаются в очередь через outbox,
+// остальные помечаются failed. Незавершённые попытки закрываются с классом lease_expired.
+func (r *TaskRepo) ReapExpiredTasks(ctx context.Context, leaseTTL time.Duration, maxAttempts, limit int) (requeued int, failed int, err error) {
+	const op = "repo.task.ReapExpiredTasks"
+
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, errutils.Wrap(op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		SELECT t.id, (SELECT COUNT(*) FROM task_attempts a WHERE a.task_id = t.id)
+		FROM tasks t
+		WHERE t.status IN ('processing', 'retrying')
+		  AND COALESCE(t.lease_until, t.started_at + $1 * INTERVAL '1 millisecond') < NOW()
+		ORDER BY t.id
+		LIMIT $2
+		FOR UPDATE OF t SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, query, leaseTTL.Milliseconds(), limit)
+	if err != nil {
+		return 0, 0, errutils.Wrap(op, err)
+	}
+
+	type expired struct {
+		id       int
+		attempts int
+	}
+	var tasks []expired
+	for rows.Next() {
+		var t expired
+		if err := rows.Scan(&t.id, &t.attempts); err != nil {
+			_ = rows.Close()
+			return 0, 0, errutils.Wrap(op, err)
+		}
+		tasks = append(tasks, t)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, errutils.Wrap(op, err)
+	}
+
+	const reason = "worker lease expired"
+	for _, t := range tasks {
+		closeAttempts := `
+			UPDATE task_attempts
+			SET finished_at = NOW(), error_class = $2, error_message = $3
+			WHERE task_id = $1 AND finished_at IS NULL
+		`
+		if _, err := tx.ExecContext(ctx, closeAttempts, t.id, domain.ErrClassLeaseExpired, reason); err != nil {
+			return 0, 0, errutils.Wrap(op, err)
+		}
+
+		if t.attempts >= maxAttempts {
+			fail := `
+				UPDATE tasks
+				SET status = 'failed', finished_at = NOW(), lease_until = NULL, failure_class = $2, failure_reason = $3
+				WHERE id = $1
+			`
+			if _, err := tx.ExecContext(ctx, fail, t.id, domain.ErrClassLeaseExpired, reason); err != nil {
+				return 0, 0, errutils.Wrap(op, err)
+			}
+			failed++
+			continue
+		}
+
+		requeue := `UPDATE tasks SET status = 'queued', lease_until = NULL WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, requeue, t.id); err != nil {
+			return 0, 0, errutils.Wrap(op, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_outbox (task_id) VALUES ($1)`, t.id); err != nil {
+			return 0, 0, errutils.Wrap(op, err)
+		}
+		requeued++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, errutils.Wrap(op, err)
+	}
+
+	return requeued, failed, nil
+}
+
+func (r *TaskRepo) CreateAttempt(ctx context.Context, taskID int, workerID string) (int, error) {
+	const op = "repo.task.CreateAttempt"
+
+	query := `INSERT INTO task_attempts (task_id, worker_id) VALUES ($1, $2) RETURNING id`
+
+	var ID int
+	if err := r.db.QueryRowContext(ctx, query, taskID, workerID).Scan(&ID); err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	return ID, nil
+}
+
+// FinishAttempt закрывает попытку и переносит её ошибку (или её отсутствие) в задачу.
+func (r *TaskRepo) FinishAttempt(ctx context.Context, attemptID int, errClass domain.ErrorClass, errMessage string) error {
+	const op = "repo.task.FinishAttempt"
+
+	query := `
+		WITH attempt AS (
+			UPDATE task_attempts
+			SET finished_at = NOW(), error_class = NULLIF($2, ''), error_message = NULLIF($3, '')
+			WHERE id = $1
+			RETURNING task_id
+		)
+		UPDATE tasks
+		SET failure_class = NULLIF($2, ''), failure_reason = NULLIF($3, '')
+		FROM attempt
+		WHERE tasks.id = attempt.task_id
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, attemptID, errClass, errMessage); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (r *TaskRepo) ListAttemptsByTaskID(ctx context.Context, taskID int) ([]domain.TaskAttempt, error) {
+	const op = "repo.task.ListAttemptsByTaskID"
+
+	query := `
+		SELECT id, task_id, worker_id, started_at, finished_at, COALESCE(error_class, ''), COALESCE(error_message, '')
+		FROM task_attempts
+		WHERE task_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+	defer rows.Close()
+
+	var attempts []domain.TaskAttempt
+	for rows.Next() {
+		var (
+			attempt    domain.TaskAttempt
+			finishedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&attempt.ID,
+			&attempt.TaskID,
+			&attempt.WorkerID,
+			&attempt.StartedAt,
+			&finishedAt,
+			&attempt.ErrorClass,
+			&attempt.ErrorMessage,
+		); err != nil {
+			return nil, errutils.Wrap(op, err)
+		}
+		if finishedAt.Valid {
+			attempt.FinishedAt = &finishedAt.Time
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	return attempts, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(s scanner) (domain.Task, error) {
+	var (
+		task       domain.Task
+		params     []byte
+		startedAt  sql.NullTime
+		finishedAt sql.NullTime
+	)
+
+	if err := s.Scan(
+		&task.ID,
+		&task.ImageID,
+		&task.ProcessedPath,
+		&task.Type,
+		&task.Status,
+		&params,
+		&task.FailureClass,
+		&task.FailureReason,
+		&task.CreatedAt,
+		&startedAt,
+		&finishedAt,
+	); err != nil {
+		return domain.Task{}, err
+	}
+
+	if err := json.Unmarshal(params, &task.Params); err != nil {
+		return domain.Task{}, err
+	}
+	if startedAt.Valid {
+		task.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		task.FinishedAt = &finishedAt.Time
+	}
+
+	return task, nil
+}
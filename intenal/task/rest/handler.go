@@ -2,15 +2,22 @@ package rest
 
 import (
 	"context"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/ilam072/image-processor/intenal/response"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"github.com/ilam072/image-processor/intenal/types/dto"
 	"github.com/wb-go/wbf/ginext"
 	"github.com/wb-go/wbf/zlog"
 	"net/http"
+	"strconv"
 )
 
 type Task interface {
-	EnqueueTask(ctx context.Context, ID uuid.UUID, action string) (int, string, error)
+	EnqueueTask(ctx context.Context, ID uuid.UUID, action string, params dto.TaskParams) (int, string, error)
+	GetTaskByID(ctx context.Context, id int) (dto.Task, error)
+	ListImageTasks(ctx context.Context, imageID uuid.UUID, pagination dto.Pagination) (dto.TaskList, error)
+	ListTaskAttempts(ctx context.Context, taskID int) ([]dto.TaskAttempt, error)
 }
 
 type Handler struct {
@@ -21,25 +28,162 @@ func NewTaskHandler(task Task) *Handler {
 	return &Handler{task: task}
 }
 
-// GET /image/:id/tasks/resize
+// GET /image/:id/tasks/resize?width=&height=&fit=&filter=&anchor=
 // GET /image/:id/tasks/thumbnail
-// GET /image/:id/tasks/watermark
+// GET /image/:id/tasks/watermark?text=&font=&font_size=&color=&logo=&logo_scale=&anchor=&offset_x=&offset_y=&opacity=&rotation=&mode=
+// GET /image/:id/tasks/crop?x=&y=&width=&height=
+// GET /image/:id/tasks/aspect_crop?aspect=&anchor=
+// GET /image/:id/tasks/smart_crop?width=&height=
+// GET /image/:id/tasks/rotate?angle=&background=
+// GET /image/:id/tasks/flip?direction=
+// GET /image/:id/tasks/variants?preset=
 func (h *Handler) EnqueueTask(action string) ginext.HandlerFunc {
 	return func(c *ginext.Context) {
 		id, err := uuid.Parse(c.Param("id"))
 		if err != nil {
-			response.Error("id must be UUID format")
+			response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
 			return
 		}
 
-		taskID, status, err := h.task.EnqueueTask(c.Request.Context(), id, action)
-		if err != nil {
-			zlog.Logger.Error().Err(err).Str("action", action).Str("image_id", id.String()).Msg("failed to enqueue task")
-			response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		var params dto.TaskParams
+		if err := c.ShouldBindQuery(&params); err != nil {
+			response.Error("invalid task params").WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+
+		h.enqueue(c, id, action, params)
+	}
+}
+
+// POST /image/:id/task/:preset
+// Ставит задачу по пресету из БД; параметры задачи заданы пресетом.
+func (h *Handler) EnqueuePreset(c *ginext.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	// Встроенные задачи ставятся своими маршрутами со своими параметрами
+	name := c.Param("preset")
+	if domain.IsBuiltinTaskType(name) {
+		response.Error("preset not found").WriteJSON(c, http.StatusNotFound)
+		return
+	}
+
+	h.enqueue(c, id, name, dto.TaskParams{})
+}
+
+// POST /image/:id/task/pipeline
+// {"operations": [{"op": "crop", "crop": {...}}, {"op": "resize", "resize": {...}}, {"op": "encode", "encode": {"format": "png"}}]}
+// Цветокоррекция и фильтры: adjust, grayscale, sepia, invert, blur, sharpen, pixelate
+func (h *Handler) EnqueuePipeline(c *ginext.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	var params dto.TaskParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		response.Error("invalid pipeline body").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	h.enqueue(c, id, "pipeline", params)
+}
+
+func (h *Handler) enqueue(c *ginext.Context, id uuid.UUID, action string, params dto.TaskParams) {
+	taskID, status, err := h.task.EnqueueTask(c.Request.Context(), id, action, params)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTaskParams) {
+			response.Error(err.Error()).WriteJSON(c, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrImageNotFound) {
+			response.Error("image not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, domain.ErrPresetNotFound) {
+			response.Error("preset not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("action", action).Str("image_id", id.String()).Msg("failed to enqueue task")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusOK, ginext.H{"task_id": taskID, "status": status})
+}
+
+// GET /task/:id
+func (h *Handler) GetTask(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error("id must be integer").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.task.GetTaskByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			response.Error("task not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Int("task_id", id).Msg("failed to get task")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusOK, task)
+}
+
+// GET /task/:id/attempts
+func (h *Handler) ListTaskAttempts(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error("id must be integer").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	attempts, err := h.task.ListTaskAttempts(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			response.Error("task not found").WriteJSON(c, http.StatusNotFound)
 			return
 		}
+		zlog.Logger.Error().Err(err).Int("task_id", id).Msg("failed to list task attempts")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
+	}
+
+	response.Raw(c, http.StatusOK, ginext.H{"task_id": id, "attempts": attempts})
+}
 
-		response.Raw(c, http.StatusOK, ginext.H{"task_id": taskID, "status": status})
+// GET /image/:id/tasks?limit=&offset=
+func (h *Handler) ListImageTasks(c *ginext.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.Error("id must be UUID format").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
+
+	var pagination dto.Pagination
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		response.Error("limit and offset must be integers").WriteJSON(c, http.StatusBadRequest)
+		return
+	}
 
+	tasks, err := h.task.ListImageTasks(c.Request.Context(), id, pagination)
+	if err != nil {
+		if errors.Is(err, domain.ErrImageNotFound) {
+			response.Error("image not found").WriteJSON(c, http.StatusNotFound)
+			return
+		}
+		zlog.Logger.Error().Err(err).Str("image_id", id.String()).Msg("failed to list image tasks")
+		response.Error("internal server error, try again later").WriteJSON(c, http.StatusInternalServerError)
+		return
 	}
+
+	response.Raw(c, http.StatusOK, tasks)
 }
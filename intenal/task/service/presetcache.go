@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	presetrepo "github.com/ilam072/image-processor/intenal/preset/repo"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPresetCacheTTL = 5 * time.Second
+	maxPresetMissTTL      = time.Second
+	maxPresetCacheEntries = 1024
+)
+
+// presetCache ненадолго запоминает результаты поиска пресетов для публичного
+// GET /image/:id?processed=<name>, чтобы он не обращался к базе на каждый запрос.
+// Отсутствие пресета помнится не дольше maxPresetMissTTL. Запись сбрасывается при изменении
+// пресета в этом процессе; изменения из других экземпляров видны не позже чем через ttl.
+type presetCache struct {
+	repo PresetRepo
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]presetCacheEntry
+}
+
+type presetCacheEntry struct {
+	preset    domain.Preset
+	found     bool
+	expiresAt time.Time
+}
+
+func newPresetCache(repo PresetRepo, ttl time.Duration) *presetCache {
+	if ttl <= 0 {
+		ttl = defaultPresetCacheTTL
+	}
+	return &presetCache{repo: repo, ttl: ttl, entries: make(map[string]presetCacheEntry)}
+}
+
+// get возвращает пресет по имени; отсутствующий пресет — presetrepo.ErrPresetNotFound, как у репозитория.
+func (c *presetCache) get(ctx context.Context, name string) (domain.Preset, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		if !entry.found {
+			return domain.Preset{}, presetrepo.ErrPresetNotFound
+		}
+		return entry.preset, nil
+	}
+
+	preset, err := c.repo.GetPresetByName(ctx, name)
+	if err != nil && !errors.Is(err, presetrepo.ErrPresetNotFound) {
+		// Ошибки базы не кэшируются
+		return domain.Preset{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxPresetCacheEntries {
+		c.evict(now)
+	}
+	ttl := c.ttl
+	if err != nil {
+		ttl = min(ttl, maxPresetMissTTL)
+	}
+	c.entries[name] = presetCacheEntry{preset: preset, found: err == nil, expiresAt: now.Add(ttl)}
+
+	return preset, err
+}
+
+func (c *presetCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, name)
+}
+
+// evict удаляет истёкшие записи, а если кэш всё ещё полон — очищает его целиком.
+func (c *presetCache) evict(now time.Time) {
+	for name, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, name)
+		}
+	}
+	if len(c.entries) >= maxPresetCacheEntries {
+		clear(c.entries)
+	}
+}
@@ -3,12 +3,17 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	imagerepo "github.com/ilam072/image-processor/intenal/image/repo"
+	presetrepo "github.com/ilam072/image-processor/intenal/preset/repo"
 	"github.com/ilam072/image-processor/intenal/task/repo"
 	"github.com/ilam072/image-processor/intenal/types/domain"
 	"github.com/ilam072/image-processor/intenal/types/dto"
 	"github.com/ilam072/image-processor/intenal/utils"
 	"github.com/ilam072/image-processor/pkg/errutils"
+	"slices"
+	"time"
 )
 
 type ImageRepo interface {
@@ -18,52 +23,122 @@ type ImageRepo interface {
 type TaskRepo interface {
 	CreateTask(ctx context.Context, task domain.Task) (int, error)
 	GetTaskByID(ctx context.Context, id int) (domain.Task, error)
+	GetLatestTaskByProcessedPath(ctx context.Context, imageID uuid.UUID, processedPath string) (domain.Task, error)
+	ListTasksByImageID(ctx context.Context, imageID uuid.UUID, limit, offset int) ([]domain.Task, error)
+	CountTasksByImageID(ctx context.Context, imageID uuid.UUID) (int, error)
 	UpdateTaskStatus(ctx context.Context, ID int, status domain.TaskStatus) error
+	RenewLease(ctx context.Context, ID int, ttl time.Duration) error
+	CreateAttempt(ctx context.Context, taskID int, workerID string) (int, error)
+	FinishAttempt(ctx context.Context, attemptID int, errClass domain.ErrorClass, errMessage string) error
+	ListAttemptsByTaskID(ctx context.Context, taskID int) ([]domain.TaskAttempt, error)
 }
 
-type Producer interface {
-	Produce(ctx context.Context, taskID int) error
+type PresetRepo interface {
+	GetPresetByName(ctx context.Context, name string) (domain.Preset, error)
+}
+
+const (
+	defaultTasksLimit = 20
+	maxTasksLimit     = 100
+)
+
+// Opts — параметры сервиса задач.
+type Opts struct {
+	VariantPresets map[string]domain.VariantPreset // пресеты задачи variants по имени
+	PresetCacheTTL time.Duration                   // сколько помнить найденный пресет при выдаче результатов
 }
 
 type Task struct {
-	imageRepo ImageRepo
-	taskRepo  TaskRepo
-	p         Producer
+	imageRepo  ImageRepo
+	taskRepo   TaskRepo
+	presetRepo PresetRepo
+	presets    *presetCache
+	opts       Opts
 }
 
-func New(imageRepo ImageRepo, taskRepo TaskRepo, p Producer) *Task {
-	return &Task{imageRepo: imageRepo, taskRepo: taskRepo, p: p}
+func New(imageRepo ImageRepo, taskRepo TaskRepo, presetRepo PresetRepo, opts Opts) *Task {
+	return &Task{
+		imageRepo:  imageRepo,
+		taskRepo:   taskRepo,
+		presetRepo: presetRepo,
+		presets:    newPresetCache(presetRepo, opts.PresetCacheTTL),
+		opts:       opts,
+	}
 }
 
-func (t *Task) EnqueueTask(ctx context.Context, ID uuid.UUID, action string) (int, string, error) {
+// InvalidatePreset сбрасывает закэшированный пресет name; вызывается при его изменении.
+func (t *Task) InvalidatePreset(name string) {
+	t.presets.invalidate(name)
+}
+
+// EnqueueTask ставит задачу action: встроенный тип задачи или имя пресета.
+func (t *Task) EnqueueTask(ctx context.Context, ID uuid.UUID, action string, params dto.TaskParams) (int, string, error) {
 	const op = "service.task.EnqueueTask"
 
 	image, err := t.imageRepo.GetImageByID(ctx, ID)
 	if err != nil {
-		if errors.Is(err, domain.ErrImageNotFound) {
-			return 0, "", errutils.Wrap(op, err)
+		if errors.Is(err, imagerepo.ErrImageNotFound) {
+			return 0, "", errutils.Wrap(op, domain.ErrImageNotFound)
 		}
 		return 0, "", errutils.Wrap(op, err)
 	}
 
-	processedPath := utils.BuildProcessedPath(image.Path, action)
+	// Новая задача всегда строится по текущему конвейеру пресета, без кэша
+	taskType, taskParams, err := t.resolveTask(ctx, action, params, image.Path, false)
+	if err != nil {
+		return 0, "", errutils.Wrap(op, err)
+	}
+
+	path, err := processedPath(image.Path, taskType, taskParams)
+	if err != nil {
+		return 0, "", errutils.Wrap(op, err)
+	}
+
 	task := domain.Task{
 		ImageID:       image.ID,
-		ProcessedPath: processedPath,
-		Type:          domain.TaskType(action),
+		ProcessedPath: path,
+		Type:          taskType,
 		Status:        domain.Queued,
+		Params:        taskParams,
 	}
 
+	// Сообщение в очередь отправит outbox relay
 	taskID, err := t.taskRepo.CreateTask(ctx, task)
 	if err != nil {
 		return 0, "", errutils.Wrap(op, err)
 	}
 
-	if err := t.p.Produce(ctx, taskID); err != nil {
-		return 0, "", errutils.Wrap(op, err)
+	return taskID, string(task.Status), nil
+}
+
+// ProcessedPath возвращает путь, по которому будет сохранён результат задачи action с параметрами params.
+// Для variants с заданной шириной возвращается путь версии этой ширины в формате format
+// (по умолчанию первом из пресета), без ширины — путь манифеста.
+func (t *Task) ProcessedPath(ctx context.Context, originalPath string, action string, params dto.TaskParams) (string, error) {
+	const op = "service.task.ProcessedPath"
+
+	taskType, taskParams, err := t.resolveTask(ctx, action, params, originalPath, true)
+	if err != nil {
+		return "", errutils.Wrap(op, err)
 	}
 
-	return taskID, string(task.Status), nil
+	path, err := processedPath(originalPath, taskType, taskParams)
+	if err != nil {
+		return "", errutils.Wrap(op, err)
+	}
+	if taskType != domain.Variants || params.Width == 0 {
+		return path, nil
+	}
+
+	if !slices.Contains(taskParams.Variants.Widths, params.Width) {
+		return "", errutils.Wrap(op, fmt.Errorf("%w: width %d is not in preset %q", domain.ErrInvalidTaskParams, params.Width, params.Preset))
+	}
+	output, ok := taskParams.Variants.Output(domain.ImageFormat(params.Format))
+	if !ok {
+		return "", errutils.Wrap(op, fmt.Errorf("%w: format %q is not in preset %q", domain.ErrInvalidTaskParams, params.Format, params.Preset))
+	}
+
+	return utils.VariantPath(path, params.Width, output.Format.Ext()), nil
 }
 
 func (t *Task) GetTaskByID(ctx context.Context, id int) (dto.Task, error) {
@@ -80,6 +155,65 @@ func (t *Task) GetTaskByID(ctx context.Context, id int) (dto.Task, error) {
 	return domainToDto(task), nil
 }
 
+// GetLatestTask возвращает последнюю задачу изображения, результат которой сохраняется по processedPath.
+func (t *Task) GetLatestTask(ctx context.Context, imageID uuid.UUID, processedPath string) (dto.Task, error) {
+	const op = "service.task.GetLatestTask"
+
+	task, err := t.taskRepo.GetLatestTaskByProcessedPath(ctx, imageID, processedPath)
+	if err != nil {
+		if errors.Is(err, repo.ErrTaskNotFound) {
+			return dto.Task{}, errutils.Wrap(op, domain.ErrTaskNotFound)
+		}
+		return dto.Task{}, errutils.Wrap(op, err)
+	}
+
+	return domainToDto(task), nil
+}
+
+func (t *Task) ListImageTasks(ctx context.Context, imageID uuid.UUID, pagination dto.Pagination) (dto.TaskList, error) {
+	const op = "service.task.ListImageTasks"
+
+	if _, err := t.imageRepo.GetImageByID(ctx, imageID); err != nil {
+		if errors.Is(err, imagerepo.ErrImageNotFound) {
+			return dto.TaskList{}, errutils.Wrap(op, domain.ErrImageNotFound)
+		}
+		return dto.TaskList{}, errutils.Wrap(op, err)
+	}
+
+	limit, offset := pagination.Limit, pagination.Offset
+	if limit <= 0 {
+		limit = defaultTasksLimit
+	}
+	if limit > maxTasksLimit {
+		limit = maxTasksLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	total, err := t.taskRepo.CountTasksByImageID(ctx, imageID)
+	if err != nil {
+		return dto.TaskList{}, errutils.Wrap(op, err)
+	}
+
+	tasks, err := t.taskRepo.ListTasksByImageID(ctx, imageID, limit, offset)
+	if err != nil {
+		return dto.TaskList{}, errutils.Wrap(op, err)
+	}
+
+	list := dto.TaskList{
+		Tasks:  make([]dto.Task, 0, len(tasks)),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, task := range tasks {
+		list.Tasks = append(list.Tasks, domainToDto(task))
+	}
+
+	return list, nil
+}
+
 func (t *Task) UpdateTaskStatus(ctx context.Context, ID int, status string) error {
 	const op = "service.task.UpdateTaskStatus"
 
@@ -91,6 +225,223 @@ func (t *Task) UpdateTaskStatus(ctx context.Context, ID int, status string) erro
 	return nil
 }
 
+// resolveTask определяет тип задачи по action и собирает её параметры. Имена, не совпадающие
+// со встроенными типами, ищутся среди пресетов, поэтому новый пресет не требует изменения маршрутов.
+func (t *Task) resolveTask(ctx context.Context, action string, params dto.TaskParams, originalPath string, cached bool) (domain.TaskType, domain.TaskParams, error) {
+	if domain.IsBuiltinTaskType(action) {
+		taskType := domain.TaskType(action)
+		taskParams, err := t.buildTaskParams(taskType, params, originalPath)
+		if err != nil {
+			return "", domain.TaskParams{}, err
+		}
+		return taskType, taskParams, nil
+	}
+
+	// Имя проверяется до запроса к базе: action приходит из публичного GET /image/:id
+	if !domain.IsPresetName(action) {
+		return "", domain.TaskParams{}, domain.ErrPresetNotFound
+	}
+
+	var (
+		preset domain.Preset
+		err    error
+	)
+	if cached {
+		preset, err = t.presets.get(ctx, action)
+	} else {
+		preset, err = t.presetRepo.GetPresetByName(ctx, action)
+	}
+	if err != nil {
+		if errors.Is(err, presetrepo.ErrPresetNotFound) {
+			return "", domain.TaskParams{}, domain.ErrPresetNotFound
+		}
+		return "", domain.TaskParams{}, err
+	}
+
+	taskParams, err := preset.TaskParams(originalPath)
+	if err != nil {
+		return "", domain.TaskParams{}, err
+	}
+
+	return domain.PresetTask, taskParams, nil
+}
+
+func (t *Task) buildTaskParams(taskType domain.TaskType, params dto.TaskParams, originalPath string) (domain.TaskParams, error) {
+	var taskParams domain.TaskParams
+
+	switch taskType {
+	case domain.Resize:
+		resize, err := domain.NewResizeParams(params.Width, params.Height, params.Fit, params.Filter, params.Anchor)
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.Resize = &resize
+	case domain.Crop:
+		crop, err := domain.NewCropParams(domain.CropParams{X: params.X, Y: params.Y, Width: params.Width, Height: params.Height})
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.Crop = &crop
+	case domain.AspectCrop:
+		aspectCrop, err := domain.NewAspectCropParams(domain.AspectCropParams{Aspect: params.Aspect, Anchor: params.Anchor})
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.AspectCrop = &aspectCrop
+	case domain.SmartCrop:
+		smartCrop, err := domain.NewSmartCropParams(domain.SmartCropParams{Width: params.Width, Height: params.Height})
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.SmartCrop = &smartCrop
+	case domain.Rotate:
+		rotate, err := domain.NewRotateParams(domain.RotateParams{Angle: params.Angle, Background: params.Background})
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.Rotate = &rotate
+	case domain.Flip:
+		flip, err := domain.NewFlipParams(domain.FlipParams{Direction: domain.FlipDirection(params.Direction)})
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.Flip = &flip
+	case domain.Watermark:
+		watermark, err := domain.NewWatermarkParams(domain.WatermarkParams{
+			Text:      params.Text,
+			Font:      params.Font,
+			FontSize:  params.FontSize,
+			Color:     params.Color,
+			Logo:      params.Logo,
+			LogoScale: params.LogoScale,
+			Anchor:    params.Anchor,
+			OffsetX:   params.OffsetX,
+			OffsetY:   params.OffsetY,
+			Opacity:   params.Opacity,
+			Rotation:  params.Rotation,
+			Mode:      domain.WatermarkMode(params.Mode),
+		})
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.Watermark = &watermark
+	case domain.Variants:
+		preset, ok := t.opts.VariantPresets[params.Preset]
+		if !ok {
+			return domain.TaskParams{}, fmt.Errorf("%w: unknown variants preset %q", domain.ErrInvalidTaskParams, params.Preset)
+		}
+		variants, err := domain.NewVariantsParams(preset, originalPath)
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.Variants = &variants
+
+		// Форматы версий заданы пресетом
+		return taskParams, nil
+	case domain.Pipeline:
+		pipeline, err := domain.NewPipeline(params.Operations)
+		if err != nil {
+			return domain.TaskParams{}, err
+		}
+		taskParams.Pipeline = pipeline
+
+		// Формат задан завершающей операцией encode
+		if _, ok := domain.PipelineOutput(pipeline); ok {
+			return taskParams, nil
+		}
+	}
+
+	output, err := domain.NewOutputParams(domain.EncodeParams{
+		Format:      domain.ImageFormat(params.Format),
+		Quality:     params.Quality,
+		Compression: domain.PNGCompression(params.Compression),
+		Colors:      params.Colors,
+		Metadata:    domain.MetadataMode(params.Metadata),
+		KeepGPS:     params.KeepGPS,
+	}, originalPath)
+	if err != nil {
+		return domain.TaskParams{}, err
+	}
+	taskParams.Output = &output
+
+	return taskParams, nil
+}
+
+func processedPath(originalPath string, taskType domain.TaskType, params domain.TaskParams) (string, error) {
+	suffix, err := domain.ProcessedSuffix(taskType, params)
+	if err != nil {
+		return "", err
+	}
+
+	path := utils.BuildProcessedPath(originalPath, suffix)
+	// Результат variants — манифест, версии сохраняются рядом с ним
+	if taskType == domain.Variants {
+		return utils.ReplaceExt(path, ".json"), nil
+	}
+	return utils.ReplaceExt(path, params.OutputFormat().Ext()), nil
+}
+
+func (t *Task) RenewLease(ctx context.Context, ID int, ttl time.Duration) error {
+	const op = "service.task.RenewLease"
+
+	if err := t.taskRepo.RenewLease(ctx, ID, ttl); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (t *Task) StartAttempt(ctx context.Context, taskID int, workerID string) (int, error) {
+	const op = "service.task.StartAttempt"
+
+	attemptID, err := t.taskRepo.CreateAttempt(ctx, taskID, workerID)
+	if err != nil {
+		return 0, errutils.Wrap(op, err)
+	}
+
+	return attemptID, nil
+}
+
+func (t *Task) FinishAttempt(ctx context.Context, attemptID int, errClass string, errMessage string) error {
+	const op = "service.task.FinishAttempt"
+
+	if err := t.taskRepo.FinishAttempt(ctx, attemptID, domain.ErrorClass(errClass), errMessage); err != nil {
+		return errutils.Wrap(op, err)
+	}
+
+	return nil
+}
+
+func (t *Task) ListTaskAttempts(ctx context.Context, taskID int) ([]dto.TaskAttempt, error) {
+	const op = "service.task.ListTaskAttempts"
+
+	if _, err := t.taskRepo.GetTaskByID(ctx, taskID); err != nil {
+		if errors.Is(err, repo.ErrTaskNotFound) {
+			return nil, errutils.Wrap(op, domain.ErrTaskNotFound)
+		}
+		return nil, errutils.Wrap(op, err)
+	}
+
+	attempts, err := t.taskRepo.ListAttemptsByTaskID(ctx, taskID)
+	if err != nil {
+		return nil, errutils.Wrap(op, err)
+	}
+
+	result := make([]dto.TaskAttempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		result = append(result, dto.TaskAttempt{
+			ID:           attempt.ID,
+			WorkerID:     attempt.WorkerID,
+			StartedAt:    attempt.StartedAt,
+			FinishedAt:   attempt.FinishedAt,
+			ErrorClass:   string(attempt.ErrorClass),
+			ErrorMessage: attempt.ErrorMessage,
+		})
+	}
+
+	return result, nil
+}
+
 func domainToDto(task domain.Task) dto.Task {
 	return dto.Task{
 		ID:            task.ID,
@@ -98,5 +449,11 @@ func domainToDto(task domain.Task) dto.Task {
 		ProcessedPath: task.ProcessedPath,
 		Type:          string(task.Type),
 		Status:        string(task.Status),
+		Params:        task.Params,
+		FailureClass:  string(task.FailureClass),
+		FailureReason: task.FailureReason,
+		CreatedAt:     task.CreatedAt,
+		StartedAt:     task.StartedAt,
+		FinishedAt:    task.FinishedAt,
 	}
 }
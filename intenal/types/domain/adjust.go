@@ -0,0 +1,131 @@
+package domain
+
+import "fmt"
+
+const (
+	DefaultSharpenSigma      = 1.0
+	DefaultSharpenAmount     = 1.0
+	DefaultPixelateBlockSize = 16
+
+	MaxBlurSigma        = 50
+	MaxPixelateRegions  = 50
+	MaxPixelateBlock    = 512
+	MaxSharpenAmount    = 10
+	MaxSharpenThreshold = 255
+)
+
+// AdjustParams — цветокоррекция. Нулевое значение поля оставляет характеристику без изменений.
+type AdjustParams struct {
+	Brightness float64 `json:"brightness,omitempty"` // проценты, -100..100
+	Contrast   float64 `json:"contrast,omitempty"`   // проценты, -100..100
+	Gamma      float64 `json:"gamma,omitempty"`      // 0.1..10, больше 1 — светлее
+	Saturation float64 `json:"saturation,omitempty"` // проценты, -100..500
+	Hue        float64 `json:"hue,omitempty"`        // сдвиг тона в градусах, -180..180
+}
+
+// BlurParams — размытие по Гауссу.
+type BlurParams struct {
+	Sigma float64 `json:"sigma"`
+}
+
+// SharpenParams — нерезкое маскирование: к пикселю добавляется Amount × (пиксель − размытый пиксель),
+// если разница по яркости больше Threshold.
+type SharpenParams struct {
+	Sigma     float64 `json:"sigma"`
+	Amount    float64 `json:"amount"`
+	Threshold int     `json:"threshold"` // 0..255
+}
+
+// PixelateParams — пикселизация блоками BlockSize. Если Regions пуст, пикселизуется всё изображение.
+type PixelateParams struct {
+	BlockSize int          `json:"block_size"`
+	Regions   []CropParams `json:"regions,omitempty"`
+}
+
+func NewAdjustParams(p AdjustParams) (AdjustParams, error) {
+	for _, v := range []float64{p.Brightness, p.Contrast, p.Gamma, p.Saturation, p.Hue} {
+		if !isFinite(v) {
+			return AdjustParams{}, fmt.Errorf("%w: adjust values must be finite numbers", ErrInvalidTaskParams)
+		}
+	}
+
+	switch {
+	case p.Brightness < -100 || p.Brightness > 100:
+		return AdjustParams{}, fmt.Errorf("%w: brightness must be between -100 and 100", ErrInvalidTaskParams)
+	case p.Contrast < -100 || p.Contrast > 100:
+		return AdjustParams{}, fmt.Errorf("%w: contrast must be between -100 and 100", ErrInvalidTaskParams)
+	case p.Gamma != 0 && (p.Gamma < 0.1 || p.Gamma > 10):
+		return AdjustParams{}, fmt.Errorf("%w: gamma must be between 0.1 and 10", ErrInvalidTaskParams)
+	case p.Saturation < -100 || p.Saturation > 500:
+		return AdjustParams{}, fmt.Errorf("%w: saturation must be between -100 and 500", ErrInvalidTaskParams)
+	case p.Hue < -180 || p.Hue > 180:
+		return AdjustParams{}, fmt.Errorf("%w: hue must be between -180 and 180", ErrInvalidTaskParams)
+	}
+
+	if p.Gamma == 1 {
+		p.Gamma = 0
+	}
+	if p == (AdjustParams{}) {
+		return AdjustParams{}, fmt.Errorf("%w: adjust requires at least one of brightness, contrast, gamma, saturation, hue", ErrInvalidTaskParams)
+	}
+
+	return p, nil
+}
+
+func NewBlurParams(p BlurParams) (BlurParams, error) {
+	if !isFinite(p.Sigma) || p.Sigma <= 0 || p.Sigma > MaxBlurSigma {
+		return BlurParams{}, fmt.Errorf("%w: blur sigma must be greater than 0 and at most %d", ErrInvalidTaskParams, MaxBlurSigma)
+	}
+	return p, nil
+}
+
+func NewSharpenParams(p SharpenParams) (SharpenParams, error) {
+	if p.Sigma == 0 {
+		p.Sigma = DefaultSharpenSigma
+	}
+	if p.Amount == 0 {
+		p.Amount = DefaultSharpenAmount
+	}
+
+	if !isFinite(p.Sigma) || !isFinite(p.Amount) {
+		return SharpenParams{}, fmt.Errorf("%w: sharpen sigma and amount must be finite numbers", ErrInvalidTaskParams)
+	}
+	if p.Sigma < 0 || p.Sigma > MaxBlurSigma {
+		return SharpenParams{}, fmt.Errorf("%w: sharpen sigma must be greater than 0 and at most %d", ErrInvalidTaskParams, MaxBlurSigma)
+	}
+	if p.Amount < 0 || p.Amount > MaxSharpenAmount {
+		return SharpenParams{}, fmt.Errorf("%w: sharpen amount must be greater than 0 and at most %d", ErrInvalidTaskParams, MaxSharpenAmount)
+	}
+	if p.Threshold < 0 || p.Threshold > MaxSharpenThreshold {
+		return SharpenParams{}, fmt.Errorf("%w: sharpen threshold must be between 0 and %d", ErrInvalidTaskParams, MaxSharpenThreshold)
+	}
+
+	return p, nil
+}
+
+func NewPixelateParams(p PixelateParams) (PixelateParams, error) {
+	if p.BlockSize == 0 {
+		p.BlockSize = DefaultPixelateBlockSize
+	}
+	if p.BlockSize < 2 || p.BlockSize > MaxPixelateBlock {
+		return PixelateParams{}, fmt.Errorf("%w: block size must be between 2 and %d", ErrInvalidTaskParams, MaxPixelateBlock)
+	}
+	if len(p.Regions) > MaxPixelateRegions {
+		return PixelateParams{}, fmt.Errorf("%w: at most %d pixelate regions are allowed", ErrInvalidTaskParams, MaxPixelateRegions)
+	}
+
+	regions := make([]CropParams, 0, len(p.Regions))
+	for i, region := range p.Regions {
+		region, err := NewCropParams(region)
+		if err != nil {
+			return PixelateParams{}, fmt.Errorf("region %d: %w", i, err)
+		}
+		regions = append(regions, region)
+	}
+	if len(regions) == 0 {
+		regions = nil
+	}
+	p.Regions = regions
+
+	return p, nil
+}
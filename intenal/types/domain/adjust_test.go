@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewAdjustParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  AdjustParams
+		wantErr bool
+	}{
+		{name: "brightness", params: AdjustParams{Brightness: 20}},
+		{name: "all bounds", params: AdjustParams{Brightness: -100, Contrast: 100, Gamma: 10, Saturation: 500, Hue: -180}},
+		{name: "empty", params: AdjustParams{}, wantErr: true},
+		{name: "neutral gamma only", params: AdjustParams{Gamma: 1}, wantErr: true},
+		{name: "brightness out of range", params: AdjustParams{Brightness: 101}, wantErr: true},
+		{name: "gamma too small", params: AdjustParams{Gamma: 0.05}, wantErr: true},
+		{name: "hue out of range", params: AdjustParams{Hue: 181}, wantErr: true},
+		{name: "NaN brightness", params: AdjustParams{Brightness: math.NaN()}, wantErr: true},
+		{name: "NaN gamma", params: AdjustParams{Gamma: math.NaN()}, wantErr: true},
+		{name: "NaN hue", params: AdjustParams{Saturation: 10, Hue: math.NaN()}, wantErr: true},
+		{name: "Inf contrast", params: AdjustParams{Contrast: math.Inf(1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAdjustParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewBlurParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		sigma   float64
+		wantErr bool
+	}{
+		{name: "valid", sigma: 2.5},
+		{name: "max", sigma: MaxBlurSigma},
+		{name: "zero", sigma: 0, wantErr: true},
+		{name: "too large", sigma: MaxBlurSigma + 1, wantErr: true},
+		{name: "NaN", sigma: math.NaN(), wantErr: true},
+		{name: "Inf", sigma: math.Inf(1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBlurParams(BlurParams{Sigma: tt.sigma})
+			checkErr(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewSharpenParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  SharpenParams
+		want    SharpenParams
+		wantErr bool
+	}{
+		{name: "defaults", params: SharpenParams{}, want: SharpenParams{Sigma: DefaultSharpenSigma, Amount: DefaultSharpenAmount}},
+		{name: "custom", params: SharpenParams{Sigma: 2, Amount: 3, Threshold: 10}, want: SharpenParams{Sigma: 2, Amount: 3, Threshold: 10}},
+		{name: "negative sigma", params: SharpenParams{Sigma: -1}, wantErr: true},
+		{name: "amount too large", params: SharpenParams{Amount: MaxSharpenAmount + 1}, wantErr: true},
+		{name: "threshold too large", params: SharpenParams{Threshold: MaxSharpenThreshold + 1}, wantErr: true},
+		{name: "NaN sigma", params: SharpenParams{Sigma: math.NaN()}, wantErr: true},
+		{name: "NaN amount", params: SharpenParams{Amount: math.NaN()}, wantErr: true},
+		{name: "Inf amount", params: SharpenParams{Amount: math.Inf(-1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSharpenParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("NewSharpenParams = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// ErrorClass — категория ошибки обработки задачи.
+type ErrorClass string
+
+const (
+	ErrClassInvalidTask  ErrorClass = "invalid_task"  // неизвестное действие или некорректные параметры
+	ErrClassNotFound     ErrorClass = "not_found"     // задача или изображение не найдены
+	ErrClassDecode       ErrorClass = "decode"        // исходный файл не удалось декодировать
+	ErrClassProcessing   ErrorClass = "processing"    // ошибка при обработке/кодировании
+	ErrClassStorage      ErrorClass = "storage"       // ошибка файлового хранилища
+	ErrClassDatabase     ErrorClass = "database"      // ошибка базы данных
+	ErrClassLeaseExpired ErrorClass = "lease_expired" // воркер перестал продлевать аренду задачи
+	ErrClassCanceled     ErrorClass = "canceled"      // задача отменена удалением изображения, результат отброшен
+	ErrClassInternal     ErrorClass = "internal"
+)
+
+type TaskAttempt struct {
+	ID           int
+	TaskID       int
+	WorkerID     string
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+	ErrorClass   ErrorClass
+	ErrorMessage string
+}
@@ -3,6 +3,18 @@ package domain
 import "errors"
 
 var (
-	ErrImageNotFound = errors.New("image not found")
-	ErrTaskNotFound  = errors.New("task not found")
+	ErrImageNotFound     = errors.New("image not found")
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrInvalidTaskParams = errors.New("invalid task params")
+	ErrUndecodableImage  = errors.New("image cannot be decoded")
+	ErrObjectNotFound    = errors.New("object not found in storage")
+	ErrPresetNotFound    = errors.New("preset not found")
+	ErrPresetExists      = errors.New("preset already exists")
+	ErrInvalidPreset     = errors.New("invalid preset")
+
+	// Ошибки проверки загружаемого файла
+	ErrEmptyFile         = errors.New("file is empty")
+	ErrFileTooLarge      = errors.New("file is too large")
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image dimensions exceed limits")
 )
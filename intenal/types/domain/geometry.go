@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FlipDirection — направление отражения.
+type FlipDirection string
+
+const (
+	FlipHorizontal FlipDirection = "horizontal"
+	FlipVertical   FlipDirection = "vertical"
+	FlipBoth       FlipDirection = "both"
+)
+
+// BackgroundTransparent — фон углов после поворота; для форматов без альфа-канала станет чёрным.
+const BackgroundTransparent = "transparent"
+
+// AspectCropParams — наибольшая область с заданным соотношением сторон, выровненная по anchor.
+type AspectCropParams struct {
+	Aspect string `json:"aspect"` // w:h, например 16:9
+	Anchor string `json:"anchor"`
+}
+
+// RotateParams — поворот на произвольный угол против часовой стрелки.
+// Изображение расширяется, чтобы поместиться целиком; углы заливаются Background.
+type RotateParams struct {
+	Angle      float64 `json:"angle"`
+	Background string  `json:"background"` // #rrggbb или transparent
+}
+
+type FlipParams struct {
+	Direction FlipDirection `json:"direction"`
+}
+
+// SmartCropParams — вырезать область с соотношением Width:Height, в которой больше всего
+// деталей (границ), и уменьшить её до Width×Height.
+type SmartCropParams struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// NewCropParams валидирует прямоугольник вырезаемой области.
+func NewCropParams(p CropParams) (CropParams, error) {
+	if p.Width <= 0 || p.Height <= 0 || p.X < 0 || p.Y < 0 {
+		return CropParams{}, fmt.Errorf("%w: crop requires non-negative x, y and positive width, height", ErrInvalidTaskParams)
+	}
+	if p.Width > MaxResizeDimension || p.Height > MaxResizeDimension {
+		return CropParams{}, fmt.Errorf("%w: crop width and height must be at most %d", ErrInvalidTaskParams, MaxResizeDimension)
+	}
+	return p, nil
+}
+
+// NewAspectCropParams приводит соотношение сторон к несократимому виду и валидирует параметры.
+func NewAspectCropParams(p AspectCropParams) (AspectCropParams, error) {
+	w, h, err := ParseAspect(p.Aspect)
+	if err != nil {
+		return AspectCropParams{}, err
+	}
+	p.Aspect = fmt.Sprintf("%d:%d", w, h)
+
+	if p.Anchor == "" {
+		p.Anchor = "center"
+	}
+	if _, ok := anchors[p.Anchor]; !ok {
+		return AspectCropParams{}, fmt.Errorf("%w: unknown anchor %q", ErrInvalidTaskParams, p.Anchor)
+	}
+
+	return p, nil
+}
+
+// ParseAspect разбирает соотношение сторон w:h и сокращает его.
+func ParseAspect(aspect string) (int, int, error) {
+	ws, hs, ok := strings.Cut(aspect, ":")
+	w, errW := strconv.Atoi(ws)
+	h, errH := strconv.Atoi(hs)
+	if !ok || errW != nil || errH != nil || w <= 0 || h <= 0 || w > MaxResizeDimension || h > MaxResizeDimension {
+		return 0, 0, fmt.Errorf("%w: aspect must be in w:h format, for example 16:9", ErrInvalidTaskParams)
+	}
+
+	d := gcd(w, h)
+	return w / d, h / d, nil
+}
+
+func NewRotateParams(p RotateParams) (RotateParams, error) {
+	if !isFinite(p.Angle) || p.Angle < -360 || p.Angle > 360 {
+		return RotateParams{}, fmt.Errorf("%w: angle must be between -360 and 360", ErrInvalidTaskParams)
+	}
+
+	p.Background = strings.ToLower(p.Background)
+	if p.Background == "" {
+		p.Background = BackgroundTransparent
+	}
+	if p.Background != BackgroundTransparent && !hexColor.MatchString(p.Background) {
+		return RotateParams{}, fmt.Errorf("%w: background must be #rrggbb or transparent", ErrInvalidTaskParams)
+	}
+
+	return p, nil
+}
+
+func NewFlipParams(p FlipParams) (FlipParams, error) {
+	if p.Direction == "" {
+		p.Direction = FlipHorizontal
+	}
+
+	switch p.Direction {
+	case FlipHorizontal, FlipVertical, FlipBoth:
+		return p, nil
+	default:
+		return FlipParams{}, fmt.Errorf("%w: unknown flip direction %q", ErrInvalidTaskParams, p.Direction)
+	}
+}
+
+func NewSmartCropParams(p SmartCropParams) (SmartCropParams, error) {
+	if p.Width <= 0 || p.Height <= 0 || p.Width > MaxResizeDimension || p.Height > MaxResizeDimension {
+		return SmartCropParams{}, fmt.Errorf("%w: smart crop requires width and height between 1 and %d", ErrInvalidTaskParams, MaxResizeDimension)
+	}
+	return p, nil
+}
+
+// Key возвращает строковое представление параметров для имени обработанного файла.
+// Например: 300x200+10+20
+func (p CropParams) Key() string {
+	return fmt.Sprintf("%dx%d+%d+%d", p.Width, p.Height, p.X, p.Y)
+}
+
+// Key возвращает строковое представление параметров для имени обработанного файла.
+// Например: 16x9_center
+func (p AspectCropParams) Key() string {
+	return fmt.Sprintf("%s_%s", strings.Replace(p.Aspect, ":", "x", 1), p.Anchor)
+}
+
+// Key возвращает строковое представление параметров для имени обработанного файла.
+// Например: 90_transparent, 45.5_ff0000
+func (p RotateParams) Key() string {
+	return fmt.Sprintf("%s_%s", strconv.FormatFloat(p.Angle, 'f', -1, 64), strings.TrimPrefix(p.Background, "#"))
+}
+
+func (p FlipParams) Key() string {
+	return string(p.Direction)
+}
+
+// Key возвращает строковое представление параметров для имени обработанного файла.
+// Например: 400x400
+func (p SmartCropParams) Key() string {
+	return fmt.Sprintf("%dx%d", p.Width, p.Height)
+}
+
+// isFinite отсекает NaN и ±Inf: сравнения с границами диапазона для NaN всегда ложны.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
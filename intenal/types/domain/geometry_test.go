@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewCropParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  CropParams
+		wantErr bool
+	}{
+		{name: "valid", params: CropParams{X: 10, Y: 20, Width: 300, Height: 200}},
+		{name: "zero origin", params: CropParams{Width: 1, Height: 1}},
+		{name: "negative x", params: CropParams{X: -1, Width: 10, Height: 10}, wantErr: true},
+		{name: "negative y", params: CropParams{Y: -1, Width: 10, Height: 10}, wantErr: true},
+		{name: "zero width", params: CropParams{Height: 10}, wantErr: true},
+		{name: "zero height", params: CropParams{Width: 10}, wantErr: true},
+		{name: "too wide", params: CropParams{Width: MaxResizeDimension + 1, Height: 10}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCropParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+			if !tt.wantErr && got != tt.params {
+				t.Errorf("NewCropParams = %+v, want %+v", got, tt.params)
+			}
+		})
+	}
+}
+
+func TestNewAspectCropParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  AspectCropParams
+		want    AspectCropParams
+		wantErr bool
+	}{
+		{name: "reduced", params: AspectCropParams{Aspect: "1920:1080"}, want: AspectCropParams{Aspect: "16:9", Anchor: "center"}},
+		{name: "anchor kept", params: AspectCropParams{Aspect: "1:1", Anchor: "top"}, want: AspectCropParams{Aspect: "1:1", Anchor: "top"}},
+		{name: "unknown anchor", params: AspectCropParams{Aspect: "1:1", Anchor: "middle"}, wantErr: true},
+		{name: "no separator", params: AspectCropParams{Aspect: "16x9"}, wantErr: true},
+		{name: "zero side", params: AspectCropParams{Aspect: "0:9"}, wantErr: true},
+		{name: "negative side", params: AspectCropParams{Aspect: "16:-9"}, wantErr: true},
+		{name: "not a number", params: AspectCropParams{Aspect: "a:b"}, wantErr: true},
+		{name: "empty", params: AspectCropParams{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAspectCropParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("NewAspectCropParams = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRotateParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  RotateParams
+		want    RotateParams
+		wantErr bool
+	}{
+		{name: "default background", params: RotateParams{Angle: 90}, want: RotateParams{Angle: 90, Background: BackgroundTransparent}},
+		{name: "color lowercased", params: RotateParams{Angle: -45.5, Background: "#FF0000"}, want: RotateParams{Angle: -45.5, Background: "#ff0000"}},
+		{name: "full turn", params: RotateParams{Angle: 360}, want: RotateParams{Angle: 360, Background: BackgroundTransparent}},
+		{name: "angle too large", params: RotateParams{Angle: 360.5}, wantErr: true},
+		{name: "angle too small", params: RotateParams{Angle: -361}, wantErr: true},
+		{name: "NaN", params: RotateParams{Angle: math.NaN()}, wantErr: true},
+		{name: "+Inf", params: RotateParams{Angle: math.Inf(1)}, wantErr: true},
+		{name: "-Inf", params: RotateParams{Angle: math.Inf(-1)}, wantErr: true},
+		{name: "invalid background", params: RotateParams{Angle: 10, Background: "red"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRotateParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("NewRotateParams = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFlipParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  FlipParams
+		want    FlipDirection
+		wantErr bool
+	}{
+		{name: "default", params: FlipParams{}, want: FlipHorizontal},
+		{name: "vertical", params: FlipParams{Direction: FlipVertical}, want: FlipVertical},
+		{name: "both", params: FlipParams{Direction: FlipBoth}, want: FlipBoth},
+		{name: "unknown", params: FlipParams{Direction: "diagonal"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFlipParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+			if !tt.wantErr && got.Direction != tt.want {
+				t.Errorf("NewFlipParams direction = %q, want %q", got.Direction, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSmartCropParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  SmartCropParams
+		wantErr bool
+	}{
+		{name: "valid", params: SmartCropParams{Width: 400, Height: 400}},
+		{name: "zero width", params: SmartCropParams{Height: 400}, wantErr: true},
+		{name: "negative height", params: SmartCropParams{Width: 400, Height: -1}, wantErr: true},
+		{name: "too large", params: SmartCropParams{Width: MaxResizeDimension + 1, Height: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSmartCropParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+		})
+	}
+}
+
+// checkErr проверяет, что ошибка есть только там, где ожидается, и что это ошибка параметров.
+func checkErr(t *testing.T, err error, wantErr bool) {
+	t.Helper()
+
+	switch {
+	case wantErr && err == nil:
+		t.Fatal("expected error, got nil")
+	case wantErr && !errors.Is(err, ErrInvalidTaskParams):
+		t.Fatalf("error %v does not wrap ErrInvalidTaskParams", err)
+	case !wantErr && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -9,4 +9,22 @@ type Image struct {
 	ID         uuid.UUID
 	Path       string
 	UploadedAt time.Time
+	DeletedAt  *time.Time // не nil, пока удаление изображения не завершено
+	Metadata   ImageMetadata
+}
+
+// ImageMetadata — сведения об оригинале, извлечённые при загрузке.
+// Width и Height — размеры как они закодированы в файле, до поворота по Orientation.
+type ImageMetadata struct {
+	OriginalFilename string
+	MimeType         string
+	Size             int64
+	Checksum         string // SHA-256 в hex
+	Width            int
+	Height           int
+	ColorModel       string
+	CameraMake       string
+	CameraModel      string
+	CapturedAt       *time.Time
+	Orientation      int // EXIF Orientation, 0 — нет данных
 }
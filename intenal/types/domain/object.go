@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// ObjectInfo — сведения об объекте в хранилище.
+type ObjectInfo struct {
+	Name         string
+	Size         int64
+	ETag         string // контрольная сумма объекта от хранилища, без кавычек
+	ContentType  string
+	LastModified time.Time
+}
@@ -0,0 +1,489 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const MaxPipelineOperations = 20
+
+type OperationType string
+
+const (
+	OpResize     OperationType = "resize"
+	OpCrop       OperationType = "crop"
+	OpAspectCrop OperationType = "aspect_crop"
+	OpSmartCrop  OperationType = "smart_crop"
+	OpRotate     OperationType = "rotate"
+	OpFlip       OperationType = "flip"
+	OpThumbnail  OperationType = "thumbnail"
+	OpWatermark  OperationType = "watermark"
+	OpAdjust     OperationType = "adjust"
+	OpGrayscale  OperationType = "grayscale"
+	OpSepia      OperationType = "sepia"
+	OpInvert     OperationType = "invert"
+	OpBlur       OperationType = "blur"
+	OpSharpen    OperationType = "sharpen"
+	OpPixelate   OperationType = "pixelate"
+	OpEncode     OperationType = "encode"
+)
+
+type ImageFormat string
+
+const (
+	FormatJPEG ImageFormat = "jpeg"
+	FormatPNG  ImageFormat = "png"
+	FormatGIF  ImageFormat = "gif"
+	FormatBMP  ImageFormat = "bmp"
+	FormatTIFF ImageFormat = "tiff"
+)
+
+const (
+	DefaultJPEGQuality = 95
+	DefaultGIFColors   = 256
+)
+
+// PNGCompression — уровень сжатия PNG.
+type PNGCompression string
+
+const (
+	PNGCompressionDefault PNGCompression = "default"
+	PNGCompressionNone    PNGCompression = "none"
+	PNGCompressionFast    PNGCompression = "fast"
+	PNGCompressionBest    PNGCompression = "best"
+)
+
+var formatExtensions = map[ImageFormat]string{
+	FormatJPEG: ".jpg",
+	FormatPNG:  ".png",
+	FormatGIF:  ".gif",
+	FormatBMP:  ".bmp",
+	FormatTIFF: ".tiff",
+}
+
+var formatContentTypes = map[ImageFormat]string{
+	FormatJPEG: "image/jpeg",
+	FormatPNG:  "image/png",
+	FormatGIF:  "image/gif",
+	FormatBMP:  "image/bmp",
+	FormatTIFF: "image/tiff",
+}
+
+// Ext возвращает расширение файла для формата.
+func (f ImageFormat) Ext() string {
+	return formatExtensions[f]
+}
+
+// ContentType возвращает MIME-тип формата.
+func (f ImageFormat) ContentType() string {
+	return formatContentTypes[f]
+}
+
+// Сигнатуры форматов в начале файла
+var formatSignatures = []struct {
+	format ImageFormat
+	magic  []string
+}{
+	{FormatJPEG, []string{"\xff\xd8\xff"}},
+	{FormatPNG, []string{"\x89PNG\r\n\x1a\n"}},
+	{FormatGIF, []string{"GIF87a", "GIF89a"}},
+	{FormatBMP, []string{"BM"}},
+	{FormatTIFF, []string{"II*\x00", "MM\x00*"}},
+}
+
+// DetectFormat определяет формат по сигнатуре (magic bytes), не доверяя имени файла.
+func DetectFormat(data []byte) (ImageFormat, bool) {
+	for _, sig := range formatSignatures {
+		for _, magic := range sig.magic {
+			if bytes.HasPrefix(data, []byte(magic)) {
+				return sig.format, true
+			}
+		}
+	}
+	return "", false
+}
+
+// FormatFromExt определяет формат по расширению файла (".jpg", ".PNG" и т.д.).
+func FormatFromExt(ext string) (ImageFormat, bool) {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg":
+		return FormatJPEG, true
+	case ".png":
+		return FormatPNG, true
+	case ".gif":
+		return FormatGIF, true
+	case ".bmp":
+		return FormatBMP, true
+	case ".tif", ".tiff":
+		return FormatTIFF, true
+	default:
+		return "", false
+	}
+}
+
+// CropParams — прямоугольник вырезаемой области в пикселях.
+type CropParams struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// MetadataMode — что делать с метаданными оригинала (EXIF, ICC-профиль, XMP) при сохранении.
+type MetadataMode string
+
+const (
+	MetadataStrip    MetadataMode = "strip"    // удалить все метаданные
+	MetadataPreserve MetadataMode = "preserve" // перенести в результат (только JPEG и PNG)
+)
+
+// EncodeParams — формат результата и настройки кодировщика.
+type EncodeParams struct {
+	Format      ImageFormat    `json:"format"`
+	Quality     int            `json:"quality,omitempty"`     // JPEG: 1-100
+	Compression PNGCompression `json:"compression,omitempty"` // PNG
+	Colors      int            `json:"colors,omitempty"`      // GIF: размер палитры 2-256
+	Metadata    MetadataMode   `json:"metadata,omitempty"`
+	KeepGPS     bool           `json:"keep_gps,omitempty"` // при preserve GPS по умолчанию удаляется
+}
+
+// NewEncodeParams подставляет настройки по умолчанию для формата и валидирует их.
+func NewEncodeParams(params EncodeParams) (EncodeParams, error) {
+	format := ImageFormat(strings.ToLower(string(params.Format)))
+	switch format {
+	case "jpg":
+		format = FormatJPEG
+	case "tif":
+		format = FormatTIFF
+	}
+	if format.Ext() == "" {
+		return EncodeParams{}, fmt.Errorf("%w: unsupported output format %q", ErrInvalidTaskParams, params.Format)
+	}
+
+	p := EncodeParams{Format: format}
+	switch format {
+	case FormatJPEG:
+		p.Quality = params.Quality
+		if p.Quality == 0 {
+			p.Quality = DefaultJPEGQuality
+		}
+		if p.Quality < 1 || p.Quality > 100 {
+			return EncodeParams{}, fmt.Errorf("%w: jpeg quality must be between 1 and 100", ErrInvalidTaskParams)
+		}
+	case FormatPNG:
+		p.Compression = params.Compression
+		if p.Compression == "" {
+			p.Compression = PNGCompressionDefault
+		}
+		switch p.Compression {
+		case PNGCompressionDefault, PNGCompressionNone, PNGCompressionFast, PNGCompressionBest:
+		default:
+			return EncodeParams{}, fmt.Errorf("%w: unknown png compression %q", ErrInvalidTaskParams, p.Compression)
+		}
+	case FormatGIF:
+		p.Colors = params.Colors
+		if p.Colors == 0 {
+			p.Colors = DefaultGIFColors
+		}
+		if p.Colors < 2 || p.Colors > 256 {
+			return EncodeParams{}, fmt.Errorf("%w: gif colors must be between 2 and 256", ErrInvalidTaskParams)
+		}
+	}
+
+	p.Metadata = params.Metadata
+	switch p.Metadata {
+	case "":
+		p.Metadata = MetadataStrip
+	case MetadataStrip, MetadataPreserve:
+	default:
+		return EncodeParams{}, fmt.Errorf("%w: unknown metadata mode %q", ErrInvalidTaskParams, p.Metadata)
+	}
+	p.KeepGPS = p.Metadata == MetadataPreserve && params.KeepGPS
+
+	return p, nil
+}
+
+// NewOutputParams собирает настройки выходного формата из параметров запроса.
+// Если формат не указан, используется формат оригинала, а для неизвестных расширений — JPEG.
+func NewOutputParams(params EncodeParams, originalPath string) (EncodeParams, error) {
+	if params.Format == "" {
+		params.Format = FormatJPEG
+		if original, ok := FormatFromExt(path.Ext(originalPath)); ok {
+			params.Format = original
+		}
+	}
+
+	return NewEncodeParams(params)
+}
+
+// Key возвращает строковое представление настроек кодировщика для имени файла.
+// Например: q95, cbest_meta, n64
+func (p EncodeParams) Key() string {
+	var parts []string
+	switch p.Format {
+	case FormatJPEG:
+		parts = append(parts, fmt.Sprintf("q%d", p.Quality))
+	case FormatPNG:
+		parts = append(parts, fmt.Sprintf("c%s", p.Compression))
+	case FormatGIF:
+		parts = append(parts, fmt.Sprintf("n%d", p.Colors))
+	}
+
+	if p.Metadata == MetadataPreserve {
+		parts = append(parts, "meta")
+		if p.KeepGPS {
+			parts = append(parts, "gps")
+		}
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// OperationParams — параметры операций; заполняется поле, соответствующее типу операции.
+type OperationParams struct {
+	Resize     *ResizeParams     `json:"resize,omitempty"`
+	Crop       *CropParams       `json:"crop,omitempty"`
+	AspectCrop *AspectCropParams `json:"aspect_crop,omitempty"`
+	SmartCrop  *SmartCropParams  `json:"smart_crop,omitempty"`
+	Rotate     *RotateParams     `json:"rotate,omitempty"`
+	Flip       *FlipParams       `json:"flip,omitempty"`
+	Watermark  *WatermarkParams  `json:"watermark,omitempty"`
+	Adjust     *AdjustParams     `json:"adjust,omitempty"`
+	Blur       *BlurParams       `json:"blur,omitempty"`
+	Sharpen    *SharpenParams    `json:"sharpen,omitempty"`
+	Pixelate   *PixelateParams   `json:"pixelate,omitempty"`
+	Encode     *EncodeParams     `json:"encode,omitempty"`
+}
+
+// Operation — шаг конвейера обработки.
+type Operation struct {
+	Type OperationType `json:"op"`
+	OperationParams
+}
+
+// NewPipeline валидирует операции конвейера и подставляет значения по умолчанию.
+// Операция encode, если есть, должна быть последней.
+func NewPipeline(ops []Operation) ([]Operation, error) {
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%w: pipeline must contain at least one operation", ErrInvalidTaskParams)
+	}
+	if len(ops) > MaxPipelineOperations {
+		return nil, fmt.Errorf("%w: pipeline must contain at most %d operations", ErrInvalidTaskParams, MaxPipelineOperations)
+	}
+
+	pipeline := make([]Operation, 0, len(ops))
+	for i, op := range ops {
+		normalized, err := normalizeOperation(op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if normalized.Type == OpEncode && i != len(ops)-1 {
+			return nil, fmt.Errorf("%w: encode must be the last operation", ErrInvalidTaskParams)
+		}
+		pipeline = append(pipeline, normalized)
+	}
+
+	return pipeline, nil
+}
+
+func normalizeOperation(op Operation) (Operation, error) {
+	switch op.Type {
+	case OpResize:
+		p := op.Resize
+		if p == nil {
+			p = &ResizeParams{}
+		}
+		resize, err := NewResizeParams(p.Width, p.Height, string(p.Fit), p.Filter, p.Anchor)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Resize: &resize}}, nil
+	case OpCrop:
+		p := op.Crop
+		if p == nil {
+			p = &CropParams{}
+		}
+		crop, err := NewCropParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Crop: &crop}}, nil
+	case OpAspectCrop:
+		p := op.AspectCrop
+		if p == nil {
+			p = &AspectCropParams{}
+		}
+		aspectCrop, err := NewAspectCropParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{AspectCrop: &aspectCrop}}, nil
+	case OpSmartCrop:
+		p := op.SmartCrop
+		if p == nil {
+			p = &SmartCropParams{}
+		}
+		smartCrop, err := NewSmartCropParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{SmartCrop: &smartCrop}}, nil
+	case OpRotate:
+		p := op.Rotate
+		if p == nil {
+			p = &RotateParams{}
+		}
+		rotate, err := NewRotateParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Rotate: &rotate}}, nil
+	case OpFlip:
+		p := op.Flip
+		if p == nil {
+			p = &FlipParams{}
+		}
+		flip, err := NewFlipParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Flip: &flip}}, nil
+	case OpThumbnail, OpGrayscale, OpSepia, OpInvert:
+		return Operation{Type: op.Type}, nil
+	case OpAdjust:
+		p := op.Adjust
+		if p == nil {
+			p = &AdjustParams{}
+		}
+		adjust, err := NewAdjustParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Adjust: &adjust}}, nil
+	case OpBlur:
+		p := op.Blur
+		if p == nil {
+			p = &BlurParams{}
+		}
+		blur, err := NewBlurParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Blur: &blur}}, nil
+	case OpSharpen:
+		p := op.Sharpen
+		if p == nil {
+			p = &SharpenParams{}
+		}
+		sharpen, err := NewSharpenParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Sharpen: &sharpen}}, nil
+	case OpPixelate:
+		p := op.Pixelate
+		if p == nil {
+			p = &PixelateParams{}
+		}
+		pixelate, err := NewPixelateParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Pixelate: &pixelate}}, nil
+	case OpWatermark:
+		p := op.Watermark
+		if p == nil {
+			p = &WatermarkParams{}
+		}
+		watermark, err := NewWatermarkParams(*p)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Watermark: &watermark}}, nil
+	case OpEncode:
+		if op.Encode == nil {
+			return Operation{}, fmt.Errorf("%w: encode requires a format", ErrInvalidTaskParams)
+		}
+		encode, err := NewEncodeParams(*op.Encode)
+		if err != nil {
+			return Operation{}, err
+		}
+		return Operation{Type: op.Type, OperationParams: OperationParams{Encode: &encode}}, nil
+	default:
+		return Operation{}, fmt.Errorf("%w: unknown operation %q", ErrInvalidTaskParams, op.Type)
+	}
+}
+
+// PipelineKey — короткий хеш нормализованного конвейера для имени обработанного файла.
+func PipelineKey(ops []Operation) string {
+	data, _ := json.Marshal(ops)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// PipelineOutput возвращает настройки из завершающей операции encode, если она есть.
+func PipelineOutput(ops []Operation) (EncodeParams, bool) {
+	if len(ops) == 0 || ops[len(ops)-1].Type != OpEncode || ops[len(ops)-1].Encode == nil {
+		return EncodeParams{}, false
+	}
+	return *ops[len(ops)-1].Encode, true
+}
+
+// OutputFormat возвращает формат, в котором будет сохранён результат задачи.
+func (p TaskParams) OutputFormat() ImageFormat {
+	if output, ok := PipelineOutput(p.Pipeline); ok {
+		return output.Format
+	}
+	if p.Output != nil {
+		return p.Output.Format
+	}
+	return FormatJPEG
+}
+
+// TaskOperations представляет задачу любого типа как конвейер операций,
+// завершающийся кодированием в выходной формат (по умолчанию JPEG).
+func TaskOperations(taskType TaskType, params TaskParams) ([]Operation, error) {
+	var ops []Operation
+
+	switch taskType {
+	case Resize:
+		if params.Resize == nil {
+			return nil, fmt.Errorf("%w: resize params are missing", ErrInvalidTaskParams)
+		}
+		ops = []Operation{{Type: OpResize, OperationParams: OperationParams{Resize: params.Resize}}}
+	case Crop, AspectCrop, SmartCrop, Rotate, Flip:
+		// Тип задачи совпадает с типом операции, параметры хранятся в одноимённом поле
+		op, err := normalizeOperation(Operation{Type: OperationType(taskType), OperationParams: params.OperationParams})
+		if err != nil {
+			return nil, err
+		}
+		ops = []Operation{op}
+	case Thumbnail:
+		ops = []Operation{{Type: OpThumbnail}}
+	case Watermark:
+		ops = []Operation{{Type: OpWatermark, OperationParams: OperationParams{Watermark: params.Watermark}}}
+	case Pipeline, PresetTask:
+		if len(params.Pipeline) == 0 {
+			return nil, fmt.Errorf("%w: pipeline operations are missing", ErrInvalidTaskParams)
+		}
+		ops = append(ops, params.Pipeline...)
+	default:
+		return nil, fmt.Errorf("%w: unexpected task type %q", ErrInvalidTaskParams, taskType)
+	}
+
+	if _, ok := PipelineOutput(ops); ok {
+		return ops, nil
+	}
+
+	output := EncodeParams{Format: FormatJPEG, Quality: DefaultJPEGQuality, Metadata: MetadataStrip}
+	if params.Output != nil {
+		output = *params.Output
+	}
+
+	return append(ops, Operation{Type: OpEncode, OperationParams: OperationParams{Encode: &output}}), nil
+}
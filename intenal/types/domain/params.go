@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"fmt"
+)
+
+const (
+	DefaultResizeWidth  = 800
+	DefaultResizeHeight = 800
+	MaxResizeDimension  = 10000
+)
+
+type FitMode string
+
+const (
+	FitContain FitMode = "fit"     // вписать в прямоугольник с сохранением пропорций
+	FitFill    FitMode = "fill"    // заполнить прямоугольник, обрезав лишнее по anchor
+	FitStretch FitMode = "stretch" // растянуть до точного размера
+)
+
+// Синонимы режимов в терминах CSS object-fit
+var fitAliases = map[string]FitMode{
+	"cover":   FitFill,
+	"contain": FitContain,
+}
+
+var resizeFilters = map[string]struct{}{
+	"nearest":    {},
+	"box":        {},
+	"linear":     {},
+	"hermite":    {},
+	"mitchell":   {},
+	"catmullrom": {},
+	"bspline":    {},
+	"gaussian":   {},
+	"lanczos":    {},
+}
+
+var anchors = map[string]struct{}{
+	"center":       {},
+	"top_left":     {},
+	"top":          {},
+	"top_right":    {},
+	"left":         {},
+	"right":        {},
+	"bottom_left":  {},
+	"bottom":       {},
+	"bottom_right": {},
+}
+
+// ResizeParams — параметры задачи resize, сохраняемые вместе с задачей.
+type ResizeParams struct {
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	Fit    FitMode `json:"fit"`
+	Filter string  `json:"filter"`
+	Anchor string  `json:"anchor"`
+}
+
+// TaskParams — параметры задачи, хранятся в колонке tasks.params.
+type TaskParams struct {
+	OperationParams
+	Pipeline []Operation     `json:"pipeline,omitempty"`
+	Variants *VariantsParams `json:"variants,omitempty"`
+	Preset   string          `json:"preset,omitempty"` // имя пресета, из которого взят Pipeline
+	Output   *EncodeParams   `json:"output,omitempty"`
+}
+
+// NewResizeParams подставляет значения по умолчанию и валидирует параметры resize.
+func NewResizeParams(width, height int, fit, filter, anchor string) (ResizeParams, error) {
+	p := ResizeParams{
+		Width:  width,
+		Height: height,
+		Fit:    FitMode(fit),
+		Filter: filter,
+		Anchor: anchor,
+	}
+
+	if p.Width == 0 && p.Height == 0 {
+		p.Width, p.Height = DefaultResizeWidth, DefaultResizeHeight
+	}
+	if alias, ok := fitAliases[fit]; ok {
+		p.Fit = alias
+	}
+	if p.Fit == "" {
+		p.Fit = FitStretch
+	}
+	if p.Filter == "" {
+		p.Filter = "lanczos"
+	}
+	if p.Anchor == "" {
+		p.Anchor = "center"
+	}
+
+	if p.Width < 0 || p.Height < 0 || p.Width > MaxResizeDimension || p.Height > MaxResizeDimension {
+		return ResizeParams{}, fmt.Errorf("%w: width and height must be between 0 and %d", ErrInvalidTaskParams, MaxResizeDimension)
+	}
+
+	switch p.Fit {
+	case FitStretch:
+	case FitContain, FitFill:
+		if p.Width == 0 || p.Height == 0 {
+			return ResizeParams{}, fmt.Errorf("%w: fit mode %q requires both width and height", ErrInvalidTaskParams, p.Fit)
+		}
+	default:
+		return ResizeParams{}, fmt.Errorf("%w: unknown fit mode %q", ErrInvalidTaskParams, p.Fit)
+	}
+
+	if _, ok := resizeFilters[p.Filter]; !ok {
+		return ResizeParams{}, fmt.Errorf("%w: unknown filter %q", ErrInvalidTaskParams, p.Filter)
+	}
+	if _, ok := anchors[p.Anchor]; !ok {
+		return ResizeParams{}, fmt.Errorf("%w: unknown anchor %q", ErrInvalidTaskParams, p.Anchor)
+	}
+
+	return p, nil
+}
+
+// Key возвращает строковое представление параметров для имени обработанного файла.
+// Например: 800x600_fill_lanczos_center
+func (p ResizeParams) Key() string {
+	return fmt.Sprintf("%dx%d_%s_%s_%s", p.Width, p.Height, p.Fit, p.Filter, p.Anchor)
+}
+
+// ProcessedSuffix формирует суффикс обработанного файла по типу задачи и её параметрам.
+func ProcessedSuffix(taskType TaskType, params TaskParams) (string, error) {
+	suffix := string(taskType)
+	switch {
+	case taskType == Resize && params.Resize != nil:
+		suffix = fmt.Sprintf("%s_%s", taskType, params.Resize.Key())
+	case taskType == Watermark && params.Watermark != nil:
+		key, err := params.Watermark.Key()
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidTaskParams, err)
+		}
+		suffix = fmt.Sprintf("%s_%s", taskType, key)
+	case taskType == Crop && params.Crop != nil:
+		suffix = fmt.Sprintf("%s_%s", taskType, params.Crop.Key())
+	case taskType == AspectCrop && params.AspectCrop != nil:
+		suffix = fmt.Sprintf("%s_%s", taskType, params.AspectCrop.Key())
+	case taskType == SmartCrop && params.SmartCrop != nil:
+		suffix = fmt.Sprintf("%s_%s", taskType, params.SmartCrop.Key())
+	case taskType == Rotate && params.Rotate != nil:
+		suffix = fmt.Sprintf("%s_%s", taskType, params.Rotate.Key())
+	case taskType == Flip && params.Flip != nil:
+		suffix = fmt.Sprintf("%s_%s", taskType, params.Flip.Key())
+	case taskType == Variants && params.Variants != nil:
+		suffix = fmt.Sprintf("%s_%s", taskType, params.Variants.Key())
+	case taskType == Pipeline:
+		suffix = fmt.Sprintf("%s_%s", taskType, PipelineKey(params.Pipeline))
+	case taskType == PresetTask:
+		// Хеш конвейера в пути: после изменения пресета результат строится заново
+		suffix = fmt.Sprintf("%s_%s_%s", taskType, params.Preset, PipelineKey(params.Pipeline))
+	}
+
+	if params.Output != nil && params.Output.Key() != "" {
+		suffix = fmt.Sprintf("%s_%s", suffix, params.Output.Key())
+	}
+
+	return suffix, nil
+}
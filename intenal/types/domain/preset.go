@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Preset — именованный конвейер операций, хранится в БД и запрашивается клиентом по имени
+// вместо встроенного типа задачи.
+type Preset struct {
+	Name      string
+	Pipeline  []Operation
+	Output    *EncodeParams // если nil и конвейер не завершается encode — формат оригинала
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IsPresetName сообщает, может ли name быть именем пресета; по нему отсекаются
+// заведомо несуществующие пресеты без запроса к базе.
+func IsPresetName(name string) bool {
+	return presetName.MatchString(name)
+}
+
+// NewPreset валидирует имя и конвейер пресета и подставляет значения по умолчанию.
+func NewPreset(p Preset) (Preset, error) {
+	if !presetName.MatchString(p.Name) {
+		return Preset{}, fmt.Errorf("%w: preset name must match %s", ErrInvalidPreset, presetName)
+	}
+	if _, ok := builtinTaskTypes[TaskType(p.Name)]; ok || TaskType(p.Name) == PresetTask {
+		return Preset{}, fmt.Errorf("%w: preset name %q is reserved", ErrInvalidPreset, p.Name)
+	}
+
+	pipeline, err := NewPipeline(p.Pipeline)
+	if err != nil {
+		return Preset{}, err
+	}
+	p.Pipeline = pipeline
+
+	if p.Output != nil {
+		if _, ok := PipelineOutput(pipeline); ok {
+			return Preset{}, fmt.Errorf("%w: output cannot be combined with a trailing encode operation", ErrInvalidPreset)
+		}
+		output, err := NewEncodeParams(*p.Output)
+		if err != nil {
+			return Preset{}, err
+		}
+		p.Output = &output
+	}
+
+	return p, nil
+}
+
+// TaskParams собирает параметры задачи из пресета. Конвейер копируется в задачу,
+// поэтому изменение пресета не влияет на уже поставленные задачи.
+func (p Preset) TaskParams(originalPath string) (TaskParams, error) {
+	params := TaskParams{Preset: p.Name, Pipeline: p.Pipeline}
+	if _, ok := PipelineOutput(p.Pipeline); ok {
+		return params, nil
+	}
+
+	output := EncodeParams{}
+	if p.Output != nil {
+		output = *p.Output
+	}
+	output, err := NewOutputParams(output, originalPath)
+	if err != nil {
+		return TaskParams{}, err
+	}
+	params.Output = &output
+
+	return params, nil
+}
@@ -8,18 +8,48 @@ import (
 type TaskType string
 
 const (
-	Resize    TaskType = "resize"
-	Thumbnail TaskType = "thumbnail"
-	Watermark TaskType = "watermark"
+	Resize     TaskType = "resize"
+	Thumbnail  TaskType = "thumbnail"
+	Watermark  TaskType = "watermark"
+	Pipeline   TaskType = "pipeline"
+	Crop       TaskType = "crop"
+	AspectCrop TaskType = "aspect_crop"
+	SmartCrop  TaskType = "smart_crop"
+	Rotate     TaskType = "rotate"
+	Flip       TaskType = "flip"
+	Variants   TaskType = "variants"
+	PresetTask TaskType = "preset" // конвейер из пресета в БД, клиент запрашивает его по имени пресета
 )
 
+// Типы задач, которые клиент запрашивает по имени; любое другое имя — пресет
+var builtinTaskTypes = map[TaskType]struct{}{
+	Resize:     {},
+	Thumbnail:  {},
+	Watermark:  {},
+	Pipeline:   {},
+	Crop:       {},
+	AspectCrop: {},
+	SmartCrop:  {},
+	Rotate:     {},
+	Flip:       {},
+	Variants:   {},
+}
+
+// IsBuiltinTaskType сообщает, является ли action встроенным типом задачи, а не именем пресета.
+func IsBuiltinTaskType(action string) bool {
+	_, ok := builtinTaskTypes[TaskType(action)]
+	return ok
+}
+
 type TaskStatus string
 
 const (
 	Queued     TaskStatus = "queued"
 	Processing TaskStatus = "processing"
+	Retrying   TaskStatus = "retrying"
 	Completed  TaskStatus = "completed"
 	Failed     TaskStatus = "failed"
+	Canceled   TaskStatus = "canceled" // изображение удалено до завершения задачи
 )
 
 type Task struct {
@@ -28,5 +58,10 @@ type Task struct {
 	ProcessedPath string
 	Type          TaskType
 	Status        TaskStatus
+	Params        TaskParams
+	FailureClass  ErrorClass
+	FailureReason string
 	CreatedAt     time.Time
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
 }
@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const MaxVariantWidths = 16
+
+var presetName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// VariantPreset — именованный набор ширин и форматов для srcset, задаётся в конфиге.
+// Если форматы не указаны, версии сохраняются в формате оригинала.
+type VariantPreset struct {
+	Name    string
+	Widths  []int
+	Formats []ImageFormat
+}
+
+// VariantsParams — параметры задачи variants. Пресет сохраняется в задаче целиком,
+// поэтому изменение конфига не влияет на уже поставленные задачи.
+type VariantsParams struct {
+	Preset  string         `json:"preset"`
+	Widths  []int          `json:"widths"`
+	Outputs []EncodeParams `json:"outputs"`
+}
+
+// VariantImage — закодированная версия изображения из набора.
+type VariantImage struct {
+	TargetWidth int // ширина из пресета, по ней строится путь версии
+	Width       int
+	Height      int
+	Format      ImageFormat
+	Data        []byte
+}
+
+// Variant — версия изображения в манифесте набора.
+type Variant struct {
+	Path        string      `json:"path"`
+	TargetWidth int         `json:"target_width"` // ширина из пресета; фактическая меньше, если оригинал уже
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
+	Format      ImageFormat `json:"format"`
+	Size        int64       `json:"size"`
+}
+
+// VariantManifest — результат задачи variants, сохраняется рядом с версиями.
+type VariantManifest struct {
+	Preset   string    `json:"preset"`
+	Variants []Variant `json:"variants"`
+}
+
+// ParseVariantPresets разбирает пресеты в формате name:w1|w2|...[:format1|format2].
+// Например: product:320|640|1024|1600:jpeg|png
+func ParseVariantPresets(raw []string) (map[string]VariantPreset, error) {
+	presets := make(map[string]VariantPreset, len(raw))
+	for _, item := range raw {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("variant preset %q must be in name:widths[:formats] format", item)
+		}
+
+		preset := VariantPreset{Name: parts[0]}
+		for _, w := range strings.Split(parts[1], "|") {
+			width, err := strconv.Atoi(strings.TrimSpace(w))
+			if err != nil {
+				return nil, fmt.Errorf("variant preset %q: invalid width %q", item, w)
+			}
+			preset.Widths = append(preset.Widths, width)
+		}
+		if len(parts) == 3 {
+			for _, f := range strings.Split(parts[2], "|") {
+				preset.Formats = append(preset.Formats, ImageFormat(strings.TrimSpace(f)))
+			}
+		}
+
+		preset, err := NewVariantPreset(preset)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := presets[preset.Name]; ok {
+			return nil, fmt.Errorf("variant preset %q is defined twice", preset.Name)
+		}
+		presets[preset.Name] = preset
+	}
+
+	return presets, nil
+}
+
+// NewVariantPreset сортирует ширины по возрастанию и валидирует пресет.
+func NewVariantPreset(p VariantPreset) (VariantPreset, error) {
+	if !presetName.MatchString(p.Name) {
+		return VariantPreset{}, fmt.Errorf("variant preset name %q must match %s", p.Name, presetName)
+	}
+	if len(p.Widths) == 0 || len(p.Widths) > MaxVariantWidths {
+		return VariantPreset{}, fmt.Errorf("variant preset %q must contain between 1 and %d widths", p.Name, MaxVariantWidths)
+	}
+
+	p.Widths = slices.Clone(p.Widths)
+	slices.Sort(p.Widths)
+	for i, w := range p.Widths {
+		if w <= 0 || w > MaxResizeDimension {
+			return VariantPreset{}, fmt.Errorf("variant preset %q: width must be between 1 and %d", p.Name, MaxResizeDimension)
+		}
+		if i > 0 && p.Widths[i-1] == w {
+			return VariantPreset{}, fmt.Errorf("variant preset %q: duplicate width %d", p.Name, w)
+		}
+	}
+
+	formats := make([]ImageFormat, 0, len(p.Formats))
+	for _, f := range p.Formats {
+		encode, err := NewEncodeParams(EncodeParams{Format: f})
+		if err != nil {
+			return VariantPreset{}, fmt.Errorf("variant preset %q: %w", p.Name, err)
+		}
+		if slices.Contains(formats, encode.Format) {
+			return VariantPreset{}, fmt.Errorf("variant preset %q: duplicate format %q", p.Name, encode.Format)
+		}
+		formats = append(formats, encode.Format)
+	}
+	p.Formats = formats
+
+	return p, nil
+}
+
+// NewVariantsParams собирает параметры задачи из пресета; без форматов в пресете
+// используется формат оригинала.
+func NewVariantsParams(preset VariantPreset, originalPath string) (VariantsParams, error) {
+	p := VariantsParams{Preset: preset.Name, Widths: preset.Widths}
+
+	formats := preset.Formats
+	if len(formats) == 0 {
+		formats = []ImageFormat{""}
+	}
+	for _, f := range formats {
+		output, err := NewOutputParams(EncodeParams{Format: f}, originalPath)
+		if err != nil {
+			return VariantsParams{}, err
+		}
+		p.Outputs = append(p.Outputs, output)
+	}
+
+	return p, nil
+}
+
+// Output возвращает настройки кодирования версии в формате format; пустой формат — первый из набора.
+func (p VariantsParams) Output(format ImageFormat) (EncodeParams, bool) {
+	if len(p.Outputs) == 0 {
+		return EncodeParams{}, false
+	}
+	if format == "" {
+		return p.Outputs[0], true
+	}
+
+	format = ImageFormat(strings.ToLower(string(format)))
+	switch format {
+	case "jpg":
+		format = FormatJPEG
+	case "tif":
+		format = FormatTIFF
+	}
+	for _, output := range p.Outputs {
+		if output.Format == format {
+			return output, true
+		}
+	}
+	return EncodeParams{}, false
+}
+
+// Key возвращает строковое представление параметров для имени обработанного файла.
+// Например: product_3f2a9c0d41be
+func (p VariantsParams) Key() string {
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s_%s", p.Preset, hex.EncodeToString(sum[:])[:12])
+}
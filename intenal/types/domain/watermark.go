@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// WatermarkMode — как водяной знак размещается на изображении.
+type WatermarkMode string
+
+const (
+	WatermarkSingle WatermarkMode = "single" // один знак у anchor со смещением
+	WatermarkTile   WatermarkMode = "tile"   // знак повторяется по всему изображению
+)
+
+const (
+	DefaultWatermarkFontSize  = 0.05 // доля меньшей стороны изображения
+	DefaultWatermarkLogoScale = 0.2  // доля ширины изображения
+	DefaultWatermarkOpacity   = 0.5
+	DefaultWatermarkColor     = "#ffffff"
+	DefaultWatermarkAnchor    = "bottom_right"
+
+	// Нижние границы размера знака: слишком мелкий знак в режиме tile повторяется
+	// на изображении сотни тысяч раз
+	MinWatermarkFontSize  = 0.01
+	MinWatermarkLogoScale = 0.05
+
+	MaxWatermarkTextLength = 200
+	MaxWatermarkOffset     = 10000 // px
+)
+
+// Шрифты и логотипы задаются именем файла в каталоге ресурсов, а не путём
+var assetName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+var hexColor = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// WatermarkParams — параметры водяного знака: текст шрифтом TTF/OTF или PNG-логотип.
+// Если не заданы ни текст, ни логотип, используется знак по умолчанию из настроек обработчика.
+type WatermarkParams struct {
+	Text      string        `json:"text,omitempty"`
+	Font      string        `json:"font,omitempty"`       // файл шрифта, пусто — Go Regular
+	FontSize  float64       `json:"font_size,omitempty"`  // высота шрифта как доля меньшей стороны изображения
+	Color     string        `json:"color,omitempty"`      // цвет текста #rrggbb
+	Logo      string        `json:"logo,omitempty"`       // файл PNG-логотипа
+	LogoScale float64       `json:"logo_scale,omitempty"` // ширина логотипа как доля ширины изображения
+	Anchor    string        `json:"anchor"`
+	OffsetX   int           `json:"offset_x"` // отступ от края по горизонтали в пикселях
+	OffsetY   int           `json:"offset_y"` // отступ от края по вертикали в пикселях
+	Opacity   float64       `json:"opacity"`
+	Rotation  float64       `json:"rotation"` // угол поворота против часовой стрелки в градусах
+	Mode      WatermarkMode `json:"mode"`
+}
+
+// NewWatermarkParams подставляет значения по умолчанию и валидирует параметры водяного знака.
+func NewWatermarkParams(p WatermarkParams) (WatermarkParams, error) {
+	if p.Text != "" && p.Logo != "" {
+		return WatermarkParams{}, fmt.Errorf("%w: watermark must be either text or logo", ErrInvalidTaskParams)
+	}
+	if utf8.RuneCountInString(p.Text) > MaxWatermarkTextLength {
+		return WatermarkParams{}, fmt.Errorf("%w: watermark text must be at most %d characters", ErrInvalidTaskParams, MaxWatermarkTextLength)
+	}
+	for _, name := range []string{p.Font, p.Logo} {
+		if name != "" && !assetName.MatchString(name) {
+			return WatermarkParams{}, fmt.Errorf("%w: invalid watermark asset name %q", ErrInvalidTaskParams, name)
+		}
+	}
+
+	for _, v := range []float64{p.FontSize, p.LogoScale, p.Opacity, p.Rotation} {
+		if !isFinite(v) {
+			return WatermarkParams{}, fmt.Errorf("%w: watermark values must be finite numbers", ErrInvalidTaskParams)
+		}
+	}
+
+	if p.FontSize == 0 {
+		p.FontSize = DefaultWatermarkFontSize
+	}
+	if p.LogoScale == 0 {
+		p.LogoScale = DefaultWatermarkLogoScale
+	}
+	p.Color = strings.ToLower(p.Color)
+	if p.Color == "" {
+		p.Color = DefaultWatermarkColor
+	}
+	if p.Anchor == "" {
+		p.Anchor = DefaultWatermarkAnchor
+	}
+	if p.Opacity == 0 {
+		p.Opacity = DefaultWatermarkOpacity
+	}
+	if p.Mode == "" {
+		p.Mode = WatermarkSingle
+	}
+
+	if p.FontSize < MinWatermarkFontSize || p.FontSize > 1 {
+		return WatermarkParams{}, fmt.Errorf("%w: font size must be between %g and 1", ErrInvalidTaskParams, MinWatermarkFontSize)
+	}
+	if p.LogoScale < MinWatermarkLogoScale || p.LogoScale > 1 {
+		return WatermarkParams{}, fmt.Errorf("%w: logo scale must be between %g and 1", ErrInvalidTaskParams, MinWatermarkLogoScale)
+	}
+	if !hexColor.MatchString(p.Color) {
+		return WatermarkParams{}, fmt.Errorf("%w: color must be in #rrggbb format", ErrInvalidTaskParams)
+	}
+	if _, ok := anchors[p.Anchor]; !ok {
+		return WatermarkParams{}, fmt.Errorf("%w: unknown anchor %q", ErrInvalidTaskParams, p.Anchor)
+	}
+	if p.OffsetX < -MaxWatermarkOffset || p.OffsetX > MaxWatermarkOffset ||
+		p.OffsetY < -MaxWatermarkOffset || p.OffsetY > MaxWatermarkOffset {
+		return WatermarkParams{}, fmt.Errorf("%w: offsets must be between -%d and %d", ErrInvalidTaskParams, MaxWatermarkOffset, MaxWatermarkOffset)
+	}
+	if p.Opacity < 0 || p.Opacity > 1 {
+		return WatermarkParams{}, fmt.Errorf("%w: opacity must be between 0 and 1", ErrInvalidTaskParams)
+	}
+	if p.Rotation < -360 || p.Rotation > 360 {
+		return WatermarkParams{}, fmt.Errorf("%w: rotation must be between -360 and 360", ErrInvalidTaskParams)
+	}
+	switch p.Mode {
+	case WatermarkSingle, WatermarkTile:
+	default:
+		return WatermarkParams{}, fmt.Errorf("%w: unknown watermark mode %q", ErrInvalidTaskParams, p.Mode)
+	}
+
+	return p, nil
+}
+
+// Key — короткий хеш нормализованных параметров для имени обработанного файла.
+func (p WatermarkParams) Key() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12], nil
+}
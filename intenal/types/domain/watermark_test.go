@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewWatermarkParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  WatermarkParams
+		wantErr bool
+	}{
+		{name: "defaults", params: WatermarkParams{}},
+		{name: "text tile", params: WatermarkParams{Text: "© Shop", FontSize: 0.1, Mode: WatermarkTile, Rotation: 30}},
+		{name: "logo", params: WatermarkParams{Logo: "logo.png", LogoScale: 0.3, Opacity: 1}},
+		{name: "text and logo", params: WatermarkParams{Text: "a", Logo: "logo.png"}, wantErr: true},
+		{name: "asset path", params: WatermarkParams{Logo: "../logo.png"}, wantErr: true},
+		{name: "font too small", params: WatermarkParams{FontSize: MinWatermarkFontSize / 2}, wantErr: true},
+		{name: "logo too small", params: WatermarkParams{LogoScale: MinWatermarkLogoScale / 2}, wantErr: true},
+		{name: "offset too large", params: WatermarkParams{OffsetX: MaxWatermarkOffset + 1}, wantErr: true},
+		{name: "unknown mode", params: WatermarkParams{Mode: "grid"}, wantErr: true},
+		{name: "NaN font size", params: WatermarkParams{FontSize: math.NaN()}, wantErr: true},
+		{name: "NaN logo scale", params: WatermarkParams{LogoScale: math.NaN()}, wantErr: true},
+		{name: "NaN opacity", params: WatermarkParams{Opacity: math.NaN()}, wantErr: true},
+		{name: "NaN rotation", params: WatermarkParams{Rotation: math.NaN()}, wantErr: true},
+		{name: "Inf rotation", params: WatermarkParams{Rotation: math.Inf(1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewWatermarkParams(tt.params)
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr {
+				return
+			}
+			if _, err := got.Key(); err != nil {
+				t.Errorf("Key: %v", err)
+			}
+		})
+	}
+}
+
+func TestWatermarkKeyError(t *testing.T) {
+	if _, err := (WatermarkParams{Rotation: math.NaN()}).Key(); err == nil {
+		t.Error("Key of non-finite params succeeded")
+	}
+	if _, err := ProcessedSuffix(Watermark, TaskParams{OperationParams: OperationParams{Watermark: &WatermarkParams{Opacity: math.Inf(1)}}}); err == nil {
+		t.Error("ProcessedSuffix of non-finite params succeeded")
+	}
+}
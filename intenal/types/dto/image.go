@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ImageMetadata struct {
+	ID               uuid.UUID  `json:"id"`
+	UploadedAt       time.Time  `json:"uploaded_at"`
+	OriginalFilename string     `json:"original_filename,omitempty"`
+	MimeType         string     `json:"mime_type,omitempty"`
+	Size             int64      `json:"size_bytes"`
+	Checksum         string     `json:"checksum_sha256,omitempty"`
+	Width            int        `json:"width"`
+	Height           int        `json:"height"`
+	ColorModel       string     `json:"color_model,omitempty"`
+	CameraMake       string     `json:"camera_make,omitempty"`
+	CameraModel      string     `json:"camera_model,omitempty"`
+	CapturedAt       *time.Time `json:"captured_at,omitempty"`
+	Orientation      int        `json:"orientation,omitempty"`
+}
+
+// TransformParams — параметры преобразования на лету из query-строки.
+type TransformParams struct {
+	Width       int    `form:"w"`
+	Height      int    `form:"h"`
+	Fit         string `form:"fit"` // fit | fill | stretch, а также cover | contain
+	Filter      string `form:"filter"`
+	Anchor      string `form:"anchor"`
+	Format      string `form:"fmt"`
+	Quality     int    `form:"q"`
+	Compression string `form:"compression"`
+	Colors      int    `form:"colors"`
+	Metadata    string `form:"metadata"`
+	KeepGPS     bool   `form:"keep_gps"`
+}
+
+// VariantManifest — набор версий изображения со ссылками для srcset.
+type VariantManifest struct {
+	ImageID   uuid.UUID         `json:"image_id"`
+	Preset    string            `json:"preset"`
+	Variants  []Variant         `json:"variants"`
+	Srcset    map[string]string `json:"srcset"` // по формату: "url 320w, url 640w"
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+}
+
+type Variant struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"`
+	Size   int64  `json:"size_bytes"`
+}
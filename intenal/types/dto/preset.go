@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"time"
+)
+
+type Preset struct {
+	Name       string               `json:"name"`
+	Operations []domain.Operation   `json:"operations"`
+	Output     *domain.EncodeParams `json:"output,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// PresetParams — тело запроса создания и изменения пресета. При изменении имя берётся из пути.
+type PresetParams struct {
+	Name       string               `json:"name"`
+	Operations []domain.Operation   `json:"operations"`
+	Output     *domain.EncodeParams `json:"output"` // если не задан и нет encode — формат оригинала
+}
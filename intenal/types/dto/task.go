@@ -1,15 +1,97 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"github.com/ilam072/image-processor/intenal/types/domain"
+	"time"
+)
 
 type Task struct {
-	ID            int
-	ImageID       uuid.UUID
-	ProcessedPath string
-	Type          string
-	Status        string
+	ID            int               `json:"id"`
+	ImageID       uuid.UUID         `json:"image_id"`
+	ProcessedPath string            `json:"processed_path"`
+	Type          string            `json:"type"`
+	Status        string            `json:"status"`
+	Params        domain.TaskParams `json:"params"`
+	FailureClass  string            `json:"failure_class,omitempty"`
+	FailureReason string            `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	StartedAt     *time.Time        `json:"started_at,omitempty"`
+	FinishedAt    *time.Time        `json:"finished_at,omitempty"`
+}
+
+type TaskAttempt struct {
+	ID           int        `json:"id"`
+	WorkerID     string     `json:"worker_id"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	ErrorClass   string     `json:"error_class,omitempty"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+}
+
+type TaskList struct {
+	Tasks  []Task `json:"tasks"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
 }
 
 type TaskMessage struct {
 	ID int
 }
+
+// DeadLetterMessage — сообщение, отправляемое в dead-letter топик после окончательной ошибки.
+type DeadLetterMessage struct {
+	TaskID     int       `json:"task_id"`
+	Payload    []byte    `json:"payload"`
+	ErrorClass string    `json:"error_class"`
+	Error      string    `json:"error"`
+	Attempts   int       `json:"attempts"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// TaskParams — параметры задачи из query-строки (resize) или тела запроса (pipeline).
+type TaskParams struct {
+	Width       int                `form:"width"`
+	Height      int                `form:"height"`
+	Fit         string             `form:"fit"`
+	Filter      string             `form:"filter"`
+	Anchor      string             `form:"anchor"`
+	Format      string             `form:"format" json:"format"`           // выходной формат, по умолчанию как у оригинала
+	Quality     int                `form:"quality" json:"quality"`         // качество JPEG
+	Compression string             `form:"compression" json:"compression"` // уровень сжатия PNG
+	Colors      int                `form:"colors" json:"colors"`           // размер палитры GIF
+	Metadata    string             `form:"metadata" json:"metadata"`       // strip или preserve
+	KeepGPS     bool               `form:"keep_gps" json:"keep_gps"`       // сохранить GPS при preserve
+	Operations  []domain.Operation `form:"-" json:"operations"`
+
+	// crop, aspect_crop, smart_crop, rotate, flip; width, height и anchor общие с resize
+	X          int     `form:"x"`
+	Y          int     `form:"y"`
+	Aspect     string  `form:"aspect"`
+	Angle      float64 `form:"angle"`
+	Background string  `form:"background"`
+	Direction  string  `form:"direction"`
+
+	// Набор версий для srcset; width и format в GET /image/:id выбирают одну версию
+	Preset string `form:"preset"`
+
+	// Водяной знак; anchor общий с resize
+	Text      string  `form:"text" json:"text"`
+	Font      string  `form:"font" json:"font"`
+	FontSize  float64 `form:"font_size" json:"font_size"`
+	Color     string  `form:"color" json:"color"`
+	Logo      string  `form:"logo" json:"logo"`
+	LogoScale float64 `form:"logo_scale" json:"logo_scale"`
+	OffsetX   int     `form:"offset_x" json:"offset_x"`
+	OffsetY   int     `form:"offset_y" json:"offset_y"`
+	Opacity   float64 `form:"opacity" json:"opacity"`
+	Rotation  float64 `form:"rotation" json:"rotation"`
+	Mode      string  `form:"mode" json:"mode"`
+}
+
+// Pagination — параметры постраничной выдачи из query-строки запроса.
+type Pagination struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
@@ -22,3 +22,27 @@ func BuildProcessedPath(originalPath, suffix string) string {
 
 	return path.Join(processedDir, processedFile)
 }
+
+// ReplaceExt заменяет расширение файла в пути.
+// Например:
+//
+//	processed/uuid_pipeline_ab12.jpg + ".png" → processed/uuid_pipeline_ab12.png
+func ReplaceExt(p, ext string) string {
+	return strings.TrimSuffix(p, path.Ext(p)) + ext
+}
+
+// ProcessedPrefix возвращает общий префикс всех обработанных версий изображения.
+// Например:
+//
+//	original/uuid.jpg → processed/uuid_
+func ProcessedPrefix(originalPath string) string {
+	return strings.TrimSuffix(BuildProcessedPath(originalPath, ""), path.Ext(originalPath))
+}
+
+// VariantPath формирует путь версии из набора по пути его манифеста.
+// Например:
+//
+//	processed/uuid_variants_product_ab12.json + 640 + ".jpg" → processed/uuid_variants_product_ab12_640w.jpg
+func VariantPath(manifestPath string, width int, ext string) string {
+	return fmt.Sprintf("%s_%dw%s", strings.TrimSuffix(manifestPath, path.Ext(manifestPath)), width, ext)
+}
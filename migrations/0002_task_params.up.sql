@@ -0,0 +1,2 @@
+-- Параметры задачи (размеры, режим вписывания, фильтр и т.д.)
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS params JSONB NOT NULL DEFAULT '{}';
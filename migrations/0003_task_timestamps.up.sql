@@ -0,0 +1,6 @@
+-- Время начала/окончания обработки и причина ошибки
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS failure_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS tasks_image_id_created_at_idx ON tasks (image_id, created_at DESC);
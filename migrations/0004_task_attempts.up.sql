@@ -0,0 +1,15 @@
+-- История попыток обработки задач
+CREATE TABLE IF NOT EXISTS task_attempts (
+        id SERIAL PRIMARY KEY,
+        task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+        worker_id TEXT NOT NULL,
+        started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+        finished_at TIMESTAMP,
+        error_class TEXT,
+        error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS task_attempts_task_id_idx ON task_attempts (task_id);
+
+-- Класс последней ошибки (сообщение хранится в failure_reason)
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS failure_class TEXT;
@@ -0,0 +1,2 @@
+-- Статус задачи, ожидающей повторной попытки
+ALTER TYPE task_status ADD VALUE IF NOT EXISTS 'retrying' AFTER 'processing';
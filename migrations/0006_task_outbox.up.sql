@@ -0,0 +1,9 @@
+-- Outbox: сообщения о новых задачах, записываемые в одной транзакции с задачей
+CREATE TABLE IF NOT EXISTS task_outbox (
+        id BIGSERIAL PRIMARY KEY,
+        task_id INT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+        created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+        sent_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS task_outbox_pending_idx ON task_outbox (id) WHERE sent_at IS NULL;
@@ -0,0 +1,4 @@
+-- Аренда задачи воркером: продлевается, пока задача в работе
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS lease_until TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS tasks_status_lease_until_idx ON tasks (status, lease_until);
@@ -0,0 +1,12 @@
+-- Очередь задач в Postgres (QUEUE_BACKEND=postgres)
+CREATE TABLE IF NOT EXISTS queue_messages (
+        id BIGSERIAL PRIMARY KEY,
+        topic TEXT NOT NULL,
+        key BYTEA,
+        value BYTEA NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+        locked_until TIMESTAMP,
+        deliveries INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS queue_messages_topic_id_idx ON queue_messages (topic, id);
@@ -0,0 +1,2 @@
+-- Конвейер операций в одной задаче
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'pipeline';
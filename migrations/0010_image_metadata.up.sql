@@ -0,0 +1,15 @@
+-- Метаданные изображения, извлечённые при загрузке
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS original_filename TEXT,
+    ADD COLUMN IF NOT EXISTS mime_type TEXT,
+    ADD COLUMN IF NOT EXISTS size_bytes BIGINT,
+    ADD COLUMN IF NOT EXISTS checksum_sha256 TEXT,
+    ADD COLUMN IF NOT EXISTS width INT,
+    ADD COLUMN IF NOT EXISTS height INT,
+    ADD COLUMN IF NOT EXISTS color_model TEXT,
+    ADD COLUMN IF NOT EXISTS camera_make TEXT,
+    ADD COLUMN IF NOT EXISTS camera_model TEXT,
+    ADD COLUMN IF NOT EXISTS captured_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS orientation SMALLINT;
+
+CREATE INDEX IF NOT EXISTS images_checksum_sha256_idx ON images(checksum_sha256);
@@ -0,0 +1,2 @@
+-- Поиск последней задачи по пути результата (GetImage с ?processed=)
+CREATE INDEX IF NOT EXISTS tasks_image_id_processed_path_idx ON tasks (image_id, processed_path, created_at DESC);
@@ -0,0 +1,4 @@
+-- Удаление изображения: строка помечается deleted_at, пока из хранилища удаляются объекты,
+-- незавершённые задачи отменяются
+ALTER TABLE images ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TYPE task_status ADD VALUE IF NOT EXISTS 'canceled';
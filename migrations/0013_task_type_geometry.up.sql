@@ -0,0 +1,6 @@
+-- Геометрические преобразования: вырезание, поворот и отражение
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'crop';
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'aspect_crop';
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'smart_crop';
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'rotate';
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'flip';
@@ -0,0 +1,2 @@
+-- Набор версий изображения разной ширины для srcset
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'variants';
@@ -0,0 +1,10 @@
+-- Именованные пресеты обработки: конвейер операций и настройки выходного формата
+CREATE TABLE IF NOT EXISTS presets (
+        name TEXT PRIMARY KEY,
+        pipeline JSONB NOT NULL,
+        output JSONB,
+        created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+        updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TYPE task_type ADD VALUE IF NOT EXISTS 'preset';
@@ -0,0 +1,54 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrCallPanicked получают ожидающие, если функция ведущего вызова запаниковала.
+var ErrCallPanicked = errors.New("singleflight: call panicked")
+
+// call — выполняющийся или завершённый вызов для ключа.
+type call[T any] struct {
+	wg  sync.WaitGroup
+	val T
+	err error
+}
+
+// Group объединяет одновременные вызовы с одинаковым ключом:
+// функция выполняется один раз, остальные ждут и получают тот же результат.
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[T]
+}
+
+// Do выполняет fn для ключа, если для него ещё нет вызова в работе, иначе ждёт его результата.
+// shared = true, если результат получен из чужого вызова.
+func (g *Group[T]) Do(key string, fn func() (T, error)) (val T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+
+	c := &call[T]{err: ErrCallPanicked}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// Ключ освобождается и при панике в fn, чтобы ожидающие не зависли навсегда
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.val, c.err = fn()
+
+	return c.val, c.err, false
+}
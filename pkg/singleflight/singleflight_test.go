@@ -0,0 +1,155 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForWaiters даёт горутинам время дойти до ожидания ведущего вызова.
+const waitForWaiters = 50 * time.Millisecond
+
+func TestDo(t *testing.T) {
+	var g Group[int]
+
+	val, err, shared := g.Do("key", func() (int, error) { return 42, nil })
+	if val != 42 || err != nil || shared {
+		t.Fatalf("Do() = %d, %v, %v, want 42, nil, false", val, err, shared)
+	}
+
+	wantErr := errors.New("boom")
+	_, err, _ = g.Do("key", func() (int, error) { return 0, wantErr })
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("Do() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestDoCoalescesConcurrentCalls(t *testing.T) {
+	const waiters = 10
+
+	var (
+		g       Group[int]
+		calls   atomic.Int32
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	fn := func() (int, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return 7, nil
+	}
+
+	type result struct {
+		val    int
+		err    error
+		shared bool
+	}
+	results := make(chan result, waiters+1)
+
+	go func() {
+		val, err, shared := g.Do("key", fn)
+		results <- result{val, err, shared}
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	for range waiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err, shared := g.Do("key", fn)
+			results <- result{val, err, shared}
+		}()
+	}
+	time.Sleep(waitForWaiters)
+	close(release)
+	wg.Wait()
+
+	var sharedCount int
+	for range waiters + 1 {
+		r := <-results
+		if r.val != 7 || r.err != nil {
+			t.Fatalf("Do() = %d, %v, want 7, nil", r.val, r.err)
+		}
+		if r.shared {
+			sharedCount++
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("fn called %d times, want 1", got)
+	}
+	if sharedCount != waiters {
+		t.Fatalf("shared results = %d, want %d", sharedCount, waiters)
+	}
+}
+
+func TestDoDistinctKeys(t *testing.T) {
+	var (
+		g       Group[string]
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+
+	// Пока вызов для "a" в работе, вызов для "b" выполняется сам
+	go func() {
+		g.Do("a", func() (string, error) {
+			close(started)
+			<-release
+			return "a", nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	val, err, shared := g.Do("b", func() (string, error) { return "b", nil })
+	if val != "b" || err != nil || shared {
+		t.Fatalf("Do(b) = %q, %v, %v, want b, nil, false", val, err, shared)
+	}
+}
+
+func TestDoPanic(t *testing.T) {
+	var (
+		g       Group[int]
+		started = make(chan struct{})
+		release = make(chan struct{})
+		leader  = make(chan any, 1)
+		waiter  = make(chan error, 1)
+	)
+
+	go func() {
+		defer func() { leader <- recover() }()
+		g.Do("key", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	go func() {
+		_, err, shared := g.Do("key", func() (int, error) { return 1, nil })
+		if !shared {
+			err = errors.New("waiter result is not shared")
+		}
+		waiter <- err
+	}()
+	time.Sleep(waitForWaiters)
+	close(release)
+
+	if r := <-leader; r != "boom" {
+		t.Fatalf("leader recovered %v, want boom", r)
+	}
+	if err := <-waiter; !errors.Is(err, ErrCallPanicked) {
+		t.Fatalf("waiter error = %v, want %v", err, ErrCallPanicked)
+	}
+
+	// После паники ключ освобождён и следующий вызов выполняется заново
+	val, err, shared := g.Do("key", func() (int, error) { return 2, nil })
+	if val != 2 || err != nil || shared {
+		t.Fatalf("Do() after panic = %d, %v, %v, want 2, nil, false", val, err, shared)
+	}
+}